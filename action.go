@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// actions lists the actions performAction knows.
+var actions = []string{"delete", "trash", "quarantine", "hardlink", "symlink", "reflink"}
+
+// actionTarget is a duplicate file chosen for an action, paired with the copy
+// from its group that is kept.
+type actionTarget struct {
+	file dupFile
+	// keep is the zero dupFile when every file of the group was chosen.
+	keep dupFile
+	size int64
+	hash string
+}
+
+// groupTargets returns a target for every file of g except the one at
+// keepIdx, which they are paired with.
+func groupTargets(g dupGroup, keepIdx int) []actionTarget {
+	targets := make([]actionTarget, 0, len(g.files)-1)
+	for i, f := range g.files {
+		if i != keepIdx {
+			targets = append(targets, actionTarget{file: f, keep: g.files[keepIdx], size: g.size, hash: g.hash})
+		}
+	}
+	return targets
+}
+
+// actionRecord describes an action performed, or planned in a dry run, on a
+// duplicate file.
+type actionRecord struct {
+	Time   time.Time `json:"time"`
+	Batch  string    `json:"batch,omitempty"`
+	Action string    `json:"action"`
+	Path   string    `json:"path"`
+	Kept   string    `json:"kept,omitempty"`
+	Size   int64     `json:"size"`
+	Hash   string    `json:"hash,omitempty"`
+	// Mode, Mtime, UID and GID are the metadata of Path before the action,
+	// used to restore it.
+	Mode  os.FileMode `json:"mode,omitempty"`
+	Mtime time.Time   `json:"mtime,omitzero"`
+	UID   int         `json:"uid"`
+	GID   int         `json:"gid"`
+	// Reclaimed is the disk space freed. Trashed and quarantined files still
+	// take up space until they are purged.
+	Reclaimed int64 `json:"reclaimed"`
+	// Dest is where a trashed or quarantined file was moved to.
+	Dest   string `json:"dest,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Warning tells about an action that succeeded in an unexpected way,
+	// such as a trashed file copied across devices.
+	Warning string `json:"warning,omitempty"`
+}
+
+// performAction applies action to t and describes the outcome. With execute
+// unset nothing is changed and the record describes what would happen.
+// Either way, t is first checked to be unchanged since the scan, and the
+// action is refused if it is not.
+// Successful actions are appended to opts.journal if it is set.
+func performAction(action string, t actionTarget, opts actionOptions, execute bool) actionRecord {
+	rec := actionRecord{
+		Time:   time.Now(),
+		Action: action,
+		Path:   t.file.path,
+		Kept:   t.keep.path,
+		Size:   t.size,
+		Hash:   t.hash,
+		Mode:   t.file.mode,
+		Mtime:  t.file.mtime,
+		UID:    t.file.sys.uid,
+		GID:    t.file.sys.gid,
+		DryRun: !execute,
+	}
+
+	var err error
+	switch action {
+	case "delete", "trash", "quarantine":
+	case "hardlink", "symlink", "reflink":
+		if t.keep.path == "" {
+			err = fmt.Errorf("no copy of %s is kept to %s to", t.file.path, action)
+		}
+	default:
+		err = fmt.Errorf("unknown action %q", action)
+	}
+
+	if err == nil && action == "symlink" {
+		if cerr := checkSymlinkTarget(t.keep.path, opts.removedFrom); cerr != nil {
+			err = fmt.Errorf("cannot link %s to %s: %w", t.file.path, t.keep.path, cerr)
+		}
+	}
+	if err == nil {
+		err = verifyTarget(t, opts.verifyContent)
+	}
+	if err == nil && execute {
+		switch action {
+		case "delete":
+			err = os.Remove(t.file.path)
+			if err == nil {
+				rec.Reclaimed = t.size
+			}
+		case "trash":
+			rec.Dest, rec.Warning, err = trashFile(t.file.path)
+		case "quarantine":
+			rec.Dest, err = quarantineFile(opts.quarantineDir, opts.rootOf(t.file.path), t.file.path)
+		case "hardlink":
+			rec.Reclaimed, err = hardlinkFile(t.file.path, t.keep.path)
+		case "symlink":
+			rec.Reclaimed, err = symlinkFile(t.file.path, t.keep.path, opts.relativeSymlinks)
+		case "reflink":
+			rec.Reclaimed, err = reflinkFile(t.file.path, t.keep.path)
+		}
+	}
+	if err == nil && execute && removes(action) && opts.removedFrom != nil {
+		opts.removedFrom[filepath.Dir(t.file.path)] = struct{}{}
+	}
+	if err == nil && execute && opts.journal != nil {
+		if jerr := opts.journal.append(rec); jerr != nil {
+			err = fmt.Errorf("%s succeeded but was not journaled: %w", action, jerr)
+		}
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	return rec
+}
+
+// actionOptions configures how actions modify files, wherever they are
+// applied from.
+type actionOptions struct {
+	// relativeSymlinks makes symlink targets relative to the link's directory.
+	relativeSymlinks bool
+	// quarantineDir, when set, makes removals move files into this directory
+	// instead of deleting them, under their path relative to the one of roots,
+	// the scanned directories, they were found in.
+	quarantineDir string
+	roots         []string
+	// trash makes removals move files to the freedesktop.org trash instead
+	// of deleting them.
+	trash bool
+	// keep chooses the file of each group that is kept.
+	keep keepRules
+	// verifyContent makes actions compare the content of files again, not
+	// only their metadata, right before modifying them.
+	verifyContent bool
+	// journal records every action that modifies files, if set.
+	journal *journal
+	// removedFrom collects the directories files were removed from during
+	// the run, if set, so that symlinks do not point into them.
+	removedFrom map[string]struct{}
+}
+
+// rootOf returns the innermost of the scanned roots that contains path, or
+// "" if none does.
+func (o actionOptions) rootOf(path string) string {
+	root := ""
+	for _, r := range o.roots {
+		if isInside(path, r) && len(r) > len(root) {
+			root = r
+		}
+	}
+	return root
+}
+
+// removeAction returns the action that removes a duplicate: quarantine or
+// trash if configured, and delete otherwise.
+func (o actionOptions) removeAction() string {
+	switch {
+	case o.quarantineDir != "":
+		return "quarantine"
+	case o.trash:
+		return "trash"
+	default:
+		return "delete"
+	}
+}
+
+// removeFile disposes of a duplicate at path: it is moved into the
+// quarantine directory or the trash if configured, and deleted otherwise.
+func (o actionOptions) removeFile(path string) error {
+	switch {
+	case o.quarantineDir != "":
+		_, err := quarantineFile(o.quarantineDir, o.rootOf(path), path)
+		return err
+	case o.trash:
+		_, warning, err := trashFile(path)
+		if warning != "" {
+			log.Printf("Warning: %s %s", path, warning)
+		}
+		return err
+	default:
+		return os.Remove(path)
+	}
+}
+
+// removeVerb describes what removeFile does, in the past tense.
+func (o actionOptions) removeVerb() string {
+	switch {
+	case o.quarantineDir != "":
+		return "quarantined"
+	case o.trash:
+		return "trashed"
+	default:
+		return "deleted"
+	}
+}
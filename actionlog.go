@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxPanelFailures is how many failures the panel shown after a batch of
+// actions lists before pointing to the log.
+const maxPanelFailures = 5
+
+// actionVerbs describes the actions in the past tense.
+var actionVerbs = map[string]string{
+	"delete":     "deleted",
+	"trash":      "trashed",
+	"quarantine": "quarantined",
+	"hardlink":   "hardlinked",
+	"symlink":    "symlinked",
+	"reflink":    "reflinked",
+	"undo":       "restored",
+}
+
+// removes reports whether action takes the file away from its path, so that
+// it no longer belongs to its group.
+func removes(action string) bool {
+	return action == "delete" || action == "trash" || action == "quarantine"
+}
+
+// applyPending applies action to every pending file. The records are kept
+// as the last batch, shown in a panel until the next key, and added to the
+// log. Files that are gone are removed from their groups.
+func (m model) applyPending(action string) (tea.Model, tea.Cmd) {
+	if m.actionOpts.journal != nil {
+		m.actionOpts.journal.startBatch()
+	}
+
+	m.batch = nil
+	m.batchPeers = m.peersOf(m.pending)
+	gone := make(map[string]struct{})
+	changed := make(map[string]struct{})
+	for _, t := range m.pending {
+		rec := performAction(action, t, m.actionOpts, true)
+		m.batch = append(m.batch, rec)
+		switch {
+		case rec.Error != "":
+		case removes(action):
+			gone[rec.Path] = struct{}{}
+		default:
+			changed[rec.Path] = struct{}{}
+			changed[rec.Kept] = struct{}{}
+		}
+	}
+	m.log = append(m.log, m.batch...)
+	m.showBatch = true
+
+	m = m.removeFiles(gone)
+	m.refreshFiles(changed)
+	m.selected = make(map[int]map[int]struct{})
+	m.pending = nil
+	m.showConfirm = false
+
+	// Linked files keep their path, so their preview must be reloaded too.
+	m.preview = filePreview{}
+	m.offset = m.scrollOffset()
+	m.overviewOffset = m.overviewScrollOffset()
+	return m, m.previewCmd()
+}
+
+// canUndo reports whether the last batch can be undone from the TUI: it
+// trashed, quarantined, hardlinked or symlinked at least one file. Deleted
+// files cannot be brought back, and an undo cannot be undone.
+func (m model) canUndo() bool {
+	if len(m.batch) == 0 {
+		return false
+	}
+	// Reflinked files were never replaced, there is nothing to restore.
+	switch m.batch[0].Action {
+	case "trash", "quarantine", "hardlink", "symlink":
+	default:
+		return false
+	}
+	for _, rec := range m.batch {
+		if rec.Error == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// undoBatch restores the files the last batch changed, as the undo command
+// does, and puts them back into their groups. The undo records replace the
+// batch, so it is not undone twice, and are added to the log and the journal.
+func (m model) undoBatch() (tea.Model, tea.Cmd) {
+	if !m.canUndo() {
+		return m, nil
+	}
+	var undone []actionRecord
+	for _, rec := range m.batch {
+		if rec.Error != "" {
+			continue
+		}
+		undo := actionRecord{Time: time.Now(), Action: "undo", Path: rec.Path, Kept: rec.Kept, Size: rec.Size, Hash: rec.Hash}
+		err := undoAction(rec)
+		if err == nil {
+			var f dupFile
+			if f, err = statDupFile(rec.Path); err == nil {
+				m = m.restoreFile(f, rec, m.batchPeers[rec.Path])
+			}
+		}
+		// The journal records the undo in the batch it undoes, which is
+		// still its current one.
+		if err == nil && m.actionOpts.journal != nil {
+			err = m.actionOpts.journal.append(undo)
+		}
+		if err != nil {
+			undo.Error = err.Error()
+		}
+		undone = append(undone, undo)
+	}
+	m.batch = undone
+	m.log = append(m.log, undone...)
+	m.showBatch = true
+	// A file that could not be restored may leave its group a single file.
+	m = m.removeFiles(nil)
+
+	m.preview = filePreview{}
+	m.offset = m.scrollOffset()
+	m.overviewOffset = m.overviewScrollOffset()
+	return m, m.previewCmd()
+}
+
+// peersOf returns the paths of the other files of the group of each target,
+// by which restoreFile finds the group again. Files of the same size and hash
+// can be in different groups, so those do not identify it.
+func (m model) peersOf(targets []actionTarget) map[string][]string {
+	peers := make(map[string][]string)
+	for _, t := range targets {
+		for _, g := range m.groups {
+			if !slices.ContainsFunc(g.files, func(f dupFile) bool { return f.path == t.file.path }) {
+				continue
+			}
+			for _, f := range g.files {
+				if f.path != t.file.path {
+					peers[t.file.path] = append(peers[t.file.path], f.path)
+				}
+			}
+			break
+		}
+	}
+	return peers
+}
+
+// restoreFile puts f back into the group that lists one of its peers,
+// replacing its entry if it is still listed. A group that was removed is
+// added back with the peers that are still there.
+func (m model) restoreFile(f dupFile, rec actionRecord, peers []string) model {
+	for groupIdx, g := range m.groups {
+		if !slices.ContainsFunc(g.files, func(d dupFile) bool { return d.path == f.path || slices.Contains(peers, d.path) }) {
+			continue
+		}
+		files := slices.Clone(g.files)
+		if i := slices.IndexFunc(files, func(d dupFile) bool { return d.path == f.path }); i >= 0 {
+			files[i] = f
+		} else {
+			files = append(files, f)
+		}
+		m.groups[groupIdx].files = files
+		return m
+	}
+	var files []dupFile
+	for _, peer := range peers {
+		if p, err := statDupFile(peer); err == nil {
+			files = append(files, p)
+		}
+	}
+	m.groups = append(m.groups, dupGroup{size: rec.Size, hash: rec.Hash, files: append(files, f)})
+	return m
+}
+
+// refreshFiles stats the files at the given paths again, so that the
+// entries of files replaced by links, and of the copies they link to, match
+// them when they are verified before the next action.
+func (m model) refreshFiles(paths map[string]struct{}) {
+	for groupIdx, g := range m.groups {
+		for fileIdx, f := range g.files {
+			if _, ok := paths[f.path]; !ok {
+				continue
+			}
+			if fresh, err := statDupFile(f.path); err == nil {
+				m.groups[groupIdx].files[fileIdx] = fresh
+			}
+		}
+	}
+}
+
+// removeFiles removes the files at the given paths from their groups, and
+// the groups left with fewer than two files, which have no duplicates to act
+// on. The cursor stays on the same file, or moves to the one that took its
+// place.
+func (m model) removeFiles(paths map[string]struct{}) model {
+	groups := make([]dupGroup, 0, len(m.groups))
+	currentGroup, currentFile := 0, 0
+	for groupIdx, g := range m.groups {
+		files := make([]dupFile, 0, len(g.files))
+		removedBefore := 0
+		for fileIdx, f := range g.files {
+			if _, gone := paths[f.path]; gone {
+				if fileIdx < m.currentFile {
+					removedBefore++
+				}
+				continue
+			}
+			files = append(files, f)
+		}
+		if groupIdx == m.currentGroup {
+			currentGroup = len(groups)
+			currentFile = max(0, min(m.currentFile-removedBefore, len(files)-1))
+		}
+		if len(files) < 2 {
+			continue
+		}
+		g.files = files
+		groups = append(groups, g)
+	}
+
+	m.groups = groups
+	m.currentGroup = max(0, min(currentGroup, len(groups)-1))
+	if m.currentGroup != currentGroup {
+		currentFile = 0
+	}
+	m.currentFile = currentFile
+	if len(groups) == 0 {
+		m.overview = true
+	}
+	return m
+}
+
+// batchSummary describes the outcome of the last batch of actions in one
+// line.
+func (m model) batchSummary() string {
+	if len(m.batch) == 0 {
+		return ""
+	}
+	done, failed := 0, 0
+	var reclaimed int64
+	for _, rec := range m.batch {
+		if rec.Error != "" {
+			failed++
+			continue
+		}
+		done++
+		reclaimed += rec.Reclaimed
+	}
+	summary := fmt.Sprintf("%d files %s", done, actionVerbs[m.batch[0].Action])
+	if reclaimed > 0 {
+		summary += fmt.Sprintf(", %s reclaimed", formatBytes(reclaimed))
+	}
+	if failed > 0 {
+		return errorStyle.Render(fmt.Sprintf("✘ %s, %d failed:", summary, failed))
+	}
+	return selectedStyle.Render("✔ " + summary)
+}
+
+// batchView renders the panel shown after a batch of actions: its summary
+// and the path and reason of each failure. It is empty once dismissed.
+func (m model) batchView() string {
+	if !m.showBatch || len(m.batch) == 0 {
+		return ""
+	}
+	lines := []string{m.batchSummary()}
+	var failures []actionRecord
+	for _, rec := range m.batch {
+		if rec.Error != "" {
+			failures = append(failures, rec)
+		}
+	}
+	for i, rec := range failures {
+		if i == maxPanelFailures {
+			lines = append(lines, helpStyle.Render(fmt.Sprintf("  … and %d more, press L to see the log", len(failures)-i)))
+			break
+		}
+		line := "  " + rec.Path + ": " + rec.Error
+		if m.width > 0 {
+			line = truncateEnd(line, m.width)
+		}
+		lines = append(lines, errorStyle.Render(line))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// logLine describes rec in the log view.
+func logLine(rec actionRecord) string {
+	line := rec.Time.Format("15:04:05") + " "
+	if rec.Error != "" {
+		return line + fmt.Sprintf("✘ %s %s: %s", rec.Action, rec.Path, rec.Error)
+	}
+	line += fmt.Sprintf("✔ %s %s", actionVerbs[rec.Action], rec.Path)
+	switch {
+	case rec.Dest != "":
+		line += " → " + rec.Dest
+	case rec.Kept != "" && !removes(rec.Action):
+		line += " → " + rec.Kept
+	}
+	if rec.Reclaimed > 0 {
+		line += fmt.Sprintf(" (%s reclaimed)", formatBytes(rec.Reclaimed))
+	}
+	if rec.Warning != "" {
+		line += " ⚠ " + rec.Warning
+	}
+	return line
+}
+
+// updateLog handles keys while the log is shown.
+func (m model) updateLog(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		m.quitting = true
+		return m, tea.Quit
+	case "L", "esc":
+		m.showLog = false
+		return m, m.previewCmd()
+	case "up", "k":
+		m.logOffset--
+	case "down", "j":
+		m.logOffset++
+	case "pgup":
+		m.logOffset -= m.listHeight()
+	case "pgdown":
+		m.logOffset += m.listHeight()
+	case "home", "g":
+		m.logOffset = 0
+	case "end", "G":
+		m.logOffset = len(m.log)
+	}
+	m.logOffset = max(0, min(m.logOffset, len(m.log)-m.listHeight()))
+	return m, nil
+}
+
+// viewLog renders the actions of the session, oldest first, scrolled to
+// logOffset.
+func (m model) viewLog() string {
+	if len(m.log) == 0 {
+		return " Log\n\n" + helpStyle.Render("No actions taken yet.")
+	}
+	start := min(m.logOffset, len(m.log))
+	end := start + min(len(m.log)-start, m.listHeight())
+	header := fmt.Sprintf(" Log (%d actions)", len(m.log))
+	if start > 0 || end < len(m.log) {
+		header += fmt.Sprintf(" • showing %d-%d", start+1, end)
+	}
+	lines := []string{header}
+	for _, rec := range m.log[start:end] {
+		line := logLine(rec)
+		if m.width > 0 {
+			line = truncateEnd(line, m.width)
+		}
+		if rec.Error != "" {
+			line = errorStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fileModel returns a model holding a group for each list of file names,
+// created with the same content in dir.
+func fileModel(t *testing.T, dir string, groups ...[]string) model {
+	t.Helper()
+	m := testModel()
+	for i, names := range groups {
+		var paths []string
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			writeTestFile(t, path, "same")
+			paths = append(paths, path)
+		}
+		g, err := newDupGroup(4, fmt.Sprint(i), paths)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.groups = append(m.groups, g)
+	}
+	return m
+}
+
+func TestModelDeleteFailures(t *testing.T) {
+	dir := t.TempDir()
+	m := fileModel(t, dir, []string{"a1", "a2", "a3", "a4"}, []string{"b1", "b2"}, []string{"c1", "c2"})
+
+	// Open the first group on a3, select a1, a2, b2 and c1, then make b2
+	// disappear before the deletion. c2 is left alone, so its group goes.
+	m = update(m, tea.KeyMsg{Type: tea.KeyHome}, tea.KeyMsg{Type: tea.KeyEnter},
+		keyRune(' '), tea.KeyMsg{Type: tea.KeyDown}, keyRune(' '), tea.KeyMsg{Type: tea.KeyDown})
+	m.selected[1] = map[int]struct{}{1: {}}
+	m.selected[2] = map[int]struct{}{0: {}}
+	if err := os.Remove(filepath.Join(dir, "b2")); err != nil {
+		t.Fatal(err)
+	}
+	m = update(m, keyRune('d'), keyRune('y'))
+
+	var remaining []string
+	for _, g := range m.groups {
+		for _, f := range g.files {
+			remaining = append(remaining, filepath.Base(f.path))
+		}
+	}
+	if strings.Join(remaining, " ") != "a3 a4 b1 b2" {
+		t.Errorf("expected a3, a4, b1 and b2 to remain, got: %v in %d groups", remaining, len(m.groups))
+	}
+	if m.currentGroup != 0 || m.currentFile != 0 {
+		t.Errorf("expected the cursor to stay on a3, got: group %d, file %d", m.currentGroup, m.currentFile)
+	}
+
+	panel := m.batchView()
+	if !strings.Contains(panel, "3 files deleted, 12 B reclaimed, 1 failed") || !strings.Contains(panel, filepath.Join(dir, "b2")+": ") {
+		t.Errorf("expected the panel to list the failure, got: %q", panel)
+	}
+	m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+	if m.batchView() != "" {
+		t.Error("expected a key to dismiss the panel")
+	}
+
+	m = update(m, keyRune('L'))
+	if !m.showLog || len(m.log) != 4 {
+		t.Fatalf("expected the log to show 4 actions, got: %v, %d", m.showLog, len(m.log))
+	}
+	view := m.View()
+	for _, want := range []string{"deleted " + filepath.Join(dir, "a1"), "✘ delete " + filepath.Join(dir, "b2")} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in the log, got: %q", want, view)
+		}
+	}
+	m = update(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.showLog || m.overview {
+		t.Error("expected Esc to close the log and go back to the group")
+	}
+}
+
+// groupNames returns the base names of the files of every group of m.
+func groupNames(m model) [][]string {
+	var names [][]string
+	for _, g := range m.groups {
+		var group []string
+		for _, f := range g.files {
+			group = append(group, filepath.Base(f.path))
+		}
+		names = append(names, group)
+	}
+	return names
+}
+
+func TestModelUndo(t *testing.T) {
+	dir := t.TempDir()
+	m := fileModel(t, dir, []string{"a1", "a2"}, []string{"b1", "b2"})
+	m.actionOpts.quarantineDir = t.TempDir()
+
+	// Quarantine a2 and the whole second group.
+	m.selected[0] = map[int]struct{}{1: {}}
+	m.selected[1] = map[int]struct{}{0: {}, 1: {}}
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter}, keyRune('d'), keyRune('y'))
+	// a1 is left without duplicates, so its group goes too.
+	if names := fmt.Sprint(groupNames(m)); names != "[]" || !m.overview {
+		t.Fatalf("expected no group to remain, got: %s", names)
+	}
+	if !m.canUndo() {
+		t.Fatal("expected the quarantine to be undoable")
+	}
+
+	m = update(m, keyRune('u'))
+	if names := fmt.Sprint(groupNames(m)); names != "[[a1 a2] [b1 b2]]" {
+		t.Errorf("expected every file back in its group, got: %s", names)
+	}
+	for _, name := range []string{"a2", "b1", "b2"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to be restored, got: %v", name, err)
+		}
+	}
+	if panel := m.batchView(); !strings.Contains(panel, "3 files restored") {
+		t.Errorf("expected the panel to report the undo, got: %q", panel)
+	}
+	if len(m.log) != 6 || m.canUndo() {
+		t.Errorf("expected the undo to be logged and not undoable, got: %d records, %v", len(m.log), m.canUndo())
+	}
+
+	// Restored files were stat'ed again, so they can be acted upon. The
+	// overview shown once every group was gone is still open.
+	m.selected[1] = map[int]struct{}{1: {}}
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter}, keyRune('d'), keyRune('y'))
+	if names := fmt.Sprint(groupNames(m)); names != "[[a1 a2]]" {
+		t.Errorf("expected b2 to be quarantined again, got: %s", names)
+	}
+
+	// Deletions cannot be undone.
+	m.actionOpts.quarantineDir = ""
+	m.selected[0] = map[int]struct{}{1: {}}
+	m = update(m, keyRune('d'), keyRune('y'))
+	if m.canUndo() {
+		t.Error("expected a deletion not to be undoable")
+	}
+}
+
+func TestModelLinkThenDelete(t *testing.T) {
+	dir := t.TempDir()
+	m := fileModel(t, dir, []string{"a1", "a2", "a3", "a4"})
+	m.selected[0] = map[int]struct{}{1: {}}
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter}, keyRune('H'), keyRune('y'))
+	if panel := m.batchView(); !strings.Contains(panel, "1 files hardlinked") {
+		t.Fatalf("expected a2 to be hardlinked, got: %q", panel)
+	}
+
+	// The linked file and its kept copy can still be acted upon.
+	m.selected[0] = map[int]struct{}{0: {}, 1: {}}
+	m = update(m, keyRune('d'), keyRune('y'))
+	if panel := m.batchView(); !strings.Contains(panel, "2 files deleted") || strings.Contains(panel, "failed") {
+		t.Errorf("expected a1 and a2 to be deleted after the link, got: %q", panel)
+	}
+	if names := fmt.Sprint(groupNames(m)); names != "[[a3 a4]]" {
+		t.Errorf("expected only a3 and a4 to remain, got: %s", names)
+	}
+}
+
+func TestModelUndoSameHash(t *testing.T) {
+	dir := t.TempDir()
+	m := fileModel(t, dir, []string{"a1", "a2"}, []string{"b1", "b2"})
+	// Groups can share their size and hash when files are split by content.
+	m.groups[1].hash = m.groups[0].hash
+	m.actionOpts.quarantineDir = t.TempDir()
+
+	m.selected[1] = map[int]struct{}{1: {}}
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter}, keyRune('d'), keyRune('y'), keyRune('u'))
+	if names := fmt.Sprint(groupNames(m)); names != "[[a1 a2] [b1 b2]]" {
+		t.Errorf("expected b2 back in its own group, got: %s", names)
+	}
+
+	m.batch = []actionRecord{{Action: "reflink", Path: filepath.Join(dir, "a2"), Kept: filepath.Join(dir, "a1")}}
+	if m.canUndo() {
+		t.Error("expected a reflink not to be undoable")
+	}
+}
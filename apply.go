@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// applyActions applies action to every duplicate of every group received on
+// results, keeping the file chosen by opts.keep, and reports each outcome to
+// r. Unless execute is set it only reports what would be done. It returns
+// the number of actions that failed.
+func applyActions(results <-chan dupGroup, action string, opts actionOptions, execute bool, r actionReporter) (int, error) {
+	if err := r.writeHeader(); err != nil {
+		return 0, err
+	}
+	failed := 0
+	for g := range results {
+		for _, t := range groupTargets(g, opts.keep.choose(g)) {
+			rec := performAction(action, t, opts, execute)
+			if rec.Error != "" {
+				failed++
+			}
+			if err := r.writeAction(rec); err != nil {
+				return failed, err
+			}
+		}
+	}
+	return failed, r.writeFooter()
+}
+
+// actionReporter writes action records in a particular output format.
+type actionReporter interface {
+	writeHeader() error
+	writeAction(rec actionRecord) error
+	writeFooter() error
+}
+
+func newActionReporter(format string, w io.Writer) (actionReporter, error) {
+	switch format {
+	case "", "default":
+		return &textActionReporter{w: w}, nil
+	case "csv":
+		return &csvActionReporter{w: csv.NewWriter(w)}, nil
+	case "json":
+		return &jsonActionReporter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("format %q cannot report actions, use default, csv or json", format)
+	}
+}
+
+type textActionReporter struct {
+	w io.Writer
+}
+
+func (r *textActionReporter) writeHeader() error { return nil }
+
+func (r *textActionReporter) writeAction(rec actionRecord) error {
+	var err error
+	switch {
+	case rec.Error != "":
+		_, err = fmt.Fprintf(r.w, "Failed to %s %s: %s\n", rec.Action, rec.Path, rec.Error)
+	case rec.DryRun:
+		_, err = fmt.Fprintf(r.w, "Would %s %s (keeping %s)\n", rec.Action, rec.Path, rec.Kept)
+	default:
+		_, err = fmt.Fprintf(r.w, "%s: %s (keeping %s)\n", actionPastTense(rec.Action), rec.Path, rec.Kept)
+	}
+	if err == nil && rec.Warning != "" {
+		_, err = fmt.Fprintf(r.w, "Warning: %s %s\n", rec.Path, rec.Warning)
+	}
+	return err
+}
+
+func (r *textActionReporter) writeFooter() error { return nil }
+
+// actionPastTense returns how an action is reported once done.
+func actionPastTense(action string) string {
+	switch action {
+	case "delete":
+		return "Deleted"
+	case "trash":
+		return "Trashed"
+	case "quarantine":
+		return "Quarantined"
+	default:
+		return "Linked (" + action + ")"
+	}
+}
+
+type csvActionReporter struct {
+	w *csv.Writer
+}
+
+func (r *csvActionReporter) writeHeader() error {
+	return r.w.Write([]string{"time", "action", "dry_run", "path", "kept", "size", "reclaimed", "dest", "error", "warning"})
+}
+
+func (r *csvActionReporter) writeAction(rec actionRecord) error {
+	return r.w.Write([]string{
+		rec.Time.Format(time.RFC3339),
+		rec.Action,
+		strconv.FormatBool(rec.DryRun),
+		rec.Path,
+		rec.Kept,
+		strconv.FormatInt(rec.Size, 10),
+		strconv.FormatInt(rec.Reclaimed, 10),
+		rec.Dest,
+		rec.Error,
+		rec.Warning,
+	})
+}
+
+func (r *csvActionReporter) writeFooter() error {
+	r.w.Flush()
+	return r.w.Error()
+}
+
+// jsonActionReporter writes a JSON array of action records, streaming them
+// as they are performed.
+type jsonActionReporter struct {
+	w     io.Writer
+	count int
+}
+
+func (r *jsonActionReporter) writeHeader() error {
+	_, err := io.WriteString(r.w, "[")
+	return err
+}
+
+func (r *jsonActionReporter) writeAction(rec actionRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	sep := "\n  "
+	if r.count > 0 {
+		sep = ",\n  "
+	}
+	r.count++
+	_, err = io.WriteString(r.w, sep+string(b))
+	return err
+}
+
+func (r *jsonActionReporter) writeFooter() error {
+	_, err := io.WriteString(r.w, "\n]\n")
+	return err
+}
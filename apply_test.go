@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// applyTestGroup creates three files with the same content and returns them
+// as a duplicate group.
+func applyTestGroup(t *testing.T, dir string) dupGroup {
+	t.Helper()
+	paths := []string{
+		filepath.Join(dir, "keep", "a.txt"),
+		filepath.Join(dir, "b.txt"),
+		filepath.Join(dir, "c.txt"),
+	}
+	for _, path := range paths {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("duplicate"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	g, err := newDupGroup(int64(len("duplicate")), "hash", paths)
+	if err != nil {
+		t.Fatalf("newDupGroup failed: %v", err)
+	}
+	return g
+}
+
+func runApply(t *testing.T, groups []dupGroup, action, format string, opts actionOptions, execute bool) (int, string) {
+	t.Helper()
+	results := make(chan dupGroup, len(groups))
+	for _, g := range groups {
+		results <- g
+	}
+	close(results)
+
+	var buf bytes.Buffer
+	r, err := newActionReporter(format, &buf)
+	if err != nil {
+		t.Fatalf("newActionReporter failed: %v", err)
+	}
+	failed, err := applyActions(results, action, opts, execute, r)
+	if err != nil {
+		t.Fatalf("applyActions failed: %v", err)
+	}
+	return failed, buf.String()
+}
+
+func TestApplyActionsDryRun(t *testing.T) {
+	tempDir := t.TempDir()
+	g := applyTestGroup(t, tempDir)
+	rules, err := parseKeepRules(keepFlag{"dir=" + filepath.Join(tempDir, "keep")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed, out := runApply(t, []dupGroup{g}, "delete", "default", actionOptions{keep: rules}, false)
+	if failed != 0 {
+		t.Errorf("expected no failures, got: %d", failed)
+	}
+	for _, f := range g.files {
+		assertFileExists(t, f.path, true)
+	}
+
+	expected := "Would delete " + g.files[1].path + " (keeping " + g.files[0].path + ")\n" +
+		"Would delete " + g.files[2].path + " (keeping " + g.files[0].path + ")\n"
+	if out != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+func TestApplyActionsExecute(t *testing.T) {
+	tests := []struct {
+		action string
+		check  func(t *testing.T, g dupGroup)
+	}{
+		{"delete", func(t *testing.T, g dupGroup) {
+			assertFileExists(t, g.files[1].path, false)
+			assertFileExists(t, g.files[2].path, false)
+		}},
+		{"hardlink", func(t *testing.T, g dupGroup) {
+			keep, _ := os.Stat(g.files[0].path)
+			for _, f := range g.files[1:] {
+				fi, err := os.Stat(f.path)
+				if err != nil || !os.SameFile(keep, fi) {
+					t.Errorf("%s is not a hardlink to %s", f.path, g.files[0].path)
+				}
+			}
+		}},
+		{"quarantine", func(t *testing.T, g dupGroup) {
+			assertFileExists(t, g.files[1].path, false)
+			assertFileExists(t, g.files[2].path, false)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			tempDir := t.TempDir()
+			g := applyTestGroup(t, tempDir)
+			rules, err := parseKeepRules(keepFlag{"dir=" + filepath.Join(tempDir, "keep")}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := actionOptions{keep: rules, quarantineDir: filepath.Join(t.TempDir(), "quarantine")}
+
+			failed, out := runApply(t, []dupGroup{g}, tt.action, "json", opts, true)
+			if failed != 0 {
+				t.Errorf("expected no failures, got: %d\n%s", failed, out)
+			}
+			assertFileExists(t, g.files[0].path, true)
+			tt.check(t, g)
+
+			var records []actionRecord
+			if err := json.Unmarshal([]byte(out), &records); err != nil {
+				t.Fatalf("output is not valid JSON: %v\n%s", err, out)
+			}
+			if len(records) != 2 {
+				t.Fatalf("expected 2 records, got: %d", len(records))
+			}
+			for _, rec := range records {
+				if rec.Action != tt.action || rec.DryRun || rec.Error != "" || rec.Kept != g.files[0].path {
+					t.Errorf("unexpected record: %+v", rec)
+				}
+				if tt.action == "quarantine" && rec.Dest == "" {
+					t.Errorf("quarantine destination of %s was not recorded", rec.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyActionsFailures(t *testing.T) {
+	tempDir := t.TempDir()
+	g := applyTestGroup(t, tempDir)
+	if err := os.Remove(g.files[2].path); err != nil {
+		t.Fatal(err)
+	}
+
+	failed, out := runApply(t, []dupGroup{g}, "delete", "csv", actionOptions{}, true)
+	if failed != 1 {
+		t.Errorf("expected 1 failure, got: %d", failed)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 records, got: %d", len(records))
+	}
+	if records[1][3] != g.files[1].path || records[1][8] != "" {
+		t.Errorf("unexpected record for %s: %v", g.files[1].path, records[1])
+	}
+	if records[2][3] != g.files[2].path || records[2][8] == "" {
+		t.Errorf("expected an error for %s, got: %v", g.files[2].path, records[2])
+	}
+}
+
+func TestPerformActionWithoutKeptCopy(t *testing.T) {
+	target := actionTarget{file: dupFile{path: "/data/a.txt"}, size: 1}
+	if rec := performAction("hardlink", target, actionOptions{}, false); rec.Error == "" {
+		t.Error("expected an error linking without a kept copy")
+	}
+	if rec := performAction("explode", target, actionOptions{}, false); rec.Error == "" {
+		t.Error("expected an error for an unknown action")
+	}
+}
+
+func TestNewActionReporterUnsupportedFormat(t *testing.T) {
+	if _, err := newActionReporter("html", &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for a format that cannot report actions, got nil")
+	}
+}
+
+func TestActionReportersWarning(t *testing.T) {
+	rec := actionRecord{Action: "trash", Path: "/mnt/a.txt", Kept: "/mnt/b.txt", Warning: "copied across devices to the home trash"}
+
+	var buf bytes.Buffer
+	r := &textActionReporter{w: &buf}
+	if err := r.writeAction(rec); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Warning: /mnt/a.txt copied across devices to the home trash\n"; !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("expected the warning to be reported, got: %q", buf.String())
+	}
+
+	if line := logLine(rec); !strings.Contains(line, rec.Warning) {
+		t.Errorf("expected the warning in the log line, got: %q", line)
+	}
+}
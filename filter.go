@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// groupFilter restricts the groups shown by the TUI. It is typed after "/"
+// as space separated terms, all of which must match:
+//
+//	photo           a file path contains "photo", ignoring case
+//	re:\.jpe?g$     a file path matches the regular expression
+//	dir:/srv/main   a file is inside /srv/main
+//	size:1M-1G      each file is between 1 MiB and 1 GiB; either bound can
+//	                be left out, as in size:100K- or size:-2G
+//
+// Values containing spaces can be double quoted, as in dir:"/srv/my files".
+type groupFilter struct {
+	text    string
+	words   []string
+	res     []*regexp.Regexp
+	dirs    []string
+	minSize int64
+	// maxSize is 0 when there is no upper bound.
+	maxSize int64
+}
+
+func parseFilter(s string) (groupFilter, error) {
+	f := groupFilter{text: strings.TrimSpace(s)}
+	for _, term := range splitFilterTerms(f.text) {
+		name, value, _ := strings.Cut(term, ":")
+		switch name {
+		case "re":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return groupFilter{}, fmt.Errorf("invalid regex: %w", err)
+			}
+			f.res = append(f.res, re)
+		case "dir":
+			dir, err := filepath.Abs(value)
+			if err != nil {
+				return groupFilter{}, err
+			}
+			f.dirs = append(f.dirs, dir)
+		case "size":
+			minStr, maxStr, ok := strings.Cut(value, "-")
+			if !ok {
+				return groupFilter{}, fmt.Errorf("invalid size range %q, use min-max", value)
+			}
+			var err error
+			if f.minSize, err = parseSize(minStr); err != nil {
+				return groupFilter{}, err
+			}
+			if f.maxSize, err = parseSize(maxStr); err != nil {
+				return groupFilter{}, err
+			}
+		default:
+			f.words = append(f.words, strings.ToLower(term))
+		}
+	}
+	return f, nil
+}
+
+// splitFilterTerms splits s at spaces outside double quotes and removes the
+// quotes.
+func splitFilterTerms(s string) []string {
+	var terms []string
+	var term strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(r)
+		}
+	}
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+	return terms
+}
+
+// parseSize parses a size such as "1500", "10K", "1.5MiB" or "2G", with
+// binary units like formatBytes. An empty string is 0.
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	num := strings.TrimRightFunc(s, unicode.IsLetter)
+	unit := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(s[len(num):]), "B"), "I")
+	n, err := strconv.ParseFloat(num, 64)
+	exp := 0
+	if unit != "" {
+		exp = strings.Index("KMGTPE", unit) + 1
+	}
+	if err != nil || n < 0 || len(unit) > 1 || (unit != "" && exp == 0) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	for range exp {
+		n *= 1024
+	}
+	return int64(n), nil
+}
+
+// active reports whether f filters anything.
+func (f groupFilter) active() bool {
+	return f.text != ""
+}
+
+// matchFile reports whether path matches every word, regex and directory
+// term of f.
+func (f groupFilter) matchFile(path string) bool {
+	lower := strings.ToLower(path)
+	for _, word := range f.words {
+		if !strings.Contains(lower, word) {
+			return false
+		}
+	}
+	for _, re := range f.res {
+		if !re.MatchString(path) {
+			return false
+		}
+	}
+	for _, dir := range f.dirs {
+		if !isInside(path, dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchGroup reports whether g is in the size range of f and has a file
+// matching it.
+func (f groupFilter) matchGroup(g dupGroup) bool {
+	if g.size < f.minSize || (f.maxSize > 0 && g.size > f.maxSize) {
+		return false
+	}
+	for _, file := range g.files {
+		if f.matchFile(file.path) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateFilterInput handles keys while the filter is being typed.
+func (m model) updateFilterInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		f, err := parseFilter(m.filterInput)
+		if err != nil {
+			m.filterErr = err.Error()
+			return m, nil
+		}
+		m.editingFilter = false
+		return m.applyFilter(f)
+	case tea.KeyEsc:
+		m.editingFilter = false
+	case tea.KeyBackspace:
+		if runes := []rune(m.filterInput); len(runes) > 0 {
+			m.filterInput = string(runes[:len(runes)-1])
+		}
+	case tea.KeyCtrlU:
+		m.filterInput = ""
+	case tea.KeySpace:
+		m.filterInput += " "
+	case tea.KeyRunes:
+		m.filterInput += string(msg.Runes)
+	}
+	m.filterErr = ""
+	return m, nil
+}
+
+// applyFilter makes f the filter of m and moves to the first matching group
+// if the current one is filtered out, going back to the overview if none
+// matches.
+func (m model) applyFilter(f groupFilter) (tea.Model, tea.Cmd) {
+	m.filter = f
+	m.filterErr = ""
+	if len(m.groups) == 0 || f.matchGroup(m.groups[m.currentGroup]) {
+		m.overviewOffset = m.overviewScrollOffset()
+		return m, nil
+	}
+	order := m.groupOrder()
+	if len(order) == 0 {
+		m.overview = true
+		return m, nil
+	}
+	m.currentGroup = order[0]
+	m.currentFile = 0
+	m.offset = 0
+	m.overviewOffset = m.overviewScrollOffset()
+	return m, m.previewCmd()
+}
+
+// nextMatch moves the cursor to the next file matching the filter, or the
+// previous one if delta is -1, continuing into the other groups in the
+// order of the overview.
+func (m model) nextMatch(delta int) model {
+	order := m.groupOrder()
+	pos := slices.Index(order, m.currentGroup)
+	if pos < 0 {
+		return m
+	}
+	groupPos, fileIdx := pos, m.currentFile
+	for range len(order) + 1 {
+		files := m.groups[order[groupPos]].files
+		for fileIdx += delta; fileIdx >= 0 && fileIdx < len(files); fileIdx += delta {
+			if m.filter.matchFile(files[fileIdx].path) {
+				m.currentGroup, m.currentFile = order[groupPos], fileIdx
+				return m
+			}
+		}
+		groupPos = (groupPos + delta + len(order)) % len(order)
+		fileIdx = -1
+		if delta < 0 {
+			fileIdx = len(m.groups[order[groupPos]].files)
+		}
+	}
+	return m
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    int64
+		expectedErr bool
+	}{
+		{input: "", expected: 0},
+		{input: "1500", expected: 1500},
+		{input: "10K", expected: 10 * 1024},
+		{input: "10kb", expected: 10 * 1024},
+		{input: "1.5MiB", expected: 1536 * 1024},
+		{input: "2G", expected: 2 << 30},
+		{input: "12B", expected: 12},
+		{input: "10X", expectedErr: true},
+		{input: "M", expectedErr: true},
+		{input: "-5", expectedErr: true},
+	}
+	for _, tt := range tests {
+		n, err := parseSize(tt.input)
+		if (err != nil) != tt.expectedErr || n != tt.expected {
+			t.Errorf("parseSize(%q): expected %d (error: %v), got: %d, %v", tt.input, tt.expected, tt.expectedErr, n, err)
+		}
+	}
+}
+
+func TestGroupFilter(t *testing.T) {
+	g := dupGroup{size: 2048, files: []dupFile{
+		{path: "/srv/main/Photos/img.JPG"},
+		{path: "/srv/my backup/img.jpg"},
+	}}
+
+	tests := []struct {
+		filter      string
+		expected    bool
+		expectedErr bool
+	}{
+		{filter: "", expected: true},
+		{filter: "photos", expected: true},
+		{filter: "videos", expected: false},
+		{filter: `re:\.jpg$`, expected: true},
+		{filter: `re:^/home/`, expected: false},
+		{filter: `dir:/srv/main`, expected: true},
+		{filter: `dir:/srv/ma`, expected: false},
+		{filter: `dir:"/srv/my backup" img`, expected: true},
+		{filter: `dir:/srv/main backup`, expected: false},
+		{filter: "size:1K-", expected: true},
+		{filter: "size:1K-2K", expected: true},
+		{filter: "size:-1K", expected: false},
+		{filter: "size:4K-1G photos", expected: false},
+		{filter: "photos img", expected: true},
+		{filter: "img photos", expected: true},
+		{filter: "photos backup", expected: false},
+		{filter: `re:^/srv/ re:\.jpg$`, expected: true},
+		{filter: `re:\.JPG$ re:backup`, expected: false},
+		{filter: `dir:/srv dir:/srv/main`, expected: true},
+		{filter: `dir:/srv/main dir:"/srv/my backup"`, expected: false},
+		{filter: "size:1K", expectedErr: true},
+		{filter: "re:(", expectedErr: true},
+	}
+	for _, tt := range tests {
+		f, err := parseFilter(tt.filter)
+		if (err != nil) != tt.expectedErr {
+			t.Errorf("parseFilter(%q): expected error: %v, got: %v", tt.filter, tt.expectedErr, err)
+			continue
+		}
+		if err == nil && f.matchGroup(g) != tt.expected {
+			t.Errorf("filter %q: expected match %v, got: %v", tt.filter, tt.expected, !tt.expected)
+		}
+	}
+}
+
+func TestModelFilter(t *testing.T) {
+	m := testModel(2, 3, 2)
+	m.groups[1].files[2].path = "/data/other/match.txt"
+	m.groups[2].files[0].path = "/data/other/match.txt"
+
+	// Type "/match" and Enter.
+	m = update(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	if !m.editingFilter {
+		t.Fatal("expected / to start editing the filter")
+	}
+	m = update(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("matc")}, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}},
+		tea.KeyMsg{Type: tea.KeyBackspace}, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'h'}}, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.editingFilter || m.filter.text != "match" {
+		t.Fatalf("expected the filter %q to be applied, got: %q (editing: %v)", "match", m.filter.text, m.editingFilter)
+	}
+	if order := m.groupOrder(); !equal(order, []int{1, 2}) {
+		t.Errorf("expected groups 1 and 2 to match, got: %v", order)
+	}
+	if m.currentGroup != 1 {
+		t.Errorf("expected the cursor to move to the first match, got: group %d", m.currentGroup)
+	}
+
+	// Groups received later are filtered too.
+	m = update(m, dupGroup{size: 1, files: []dupFile{{path: "/x/a"}, {path: "/x/b"}}},
+		dupGroup{size: 1, files: []dupFile{{path: "/x/match"}, {path: "/x/b"}}})
+	if order := m.groupOrder(); !equal(order, []int{1, 2, 4}) {
+		t.Errorf("expected groups 1, 2 and 4 to match, got: %v", order)
+	}
+
+	// n jumps from match to match across groups, and N goes back.
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter})
+	var visited [][2]int
+	for range 4 {
+		m = update(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+		visited = append(visited, [2]int{m.currentGroup, m.currentFile})
+	}
+	expected := [][2]int{{1, 2}, {2, 0}, {4, 0}, {1, 2}}
+	for i := range expected {
+		if visited[i] != expected[i] {
+			t.Fatalf("expected n to visit %v, got: %v", expected, visited)
+		}
+	}
+	m = update(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'N'}})
+	if m.currentGroup != 4 || m.currentFile != 0 {
+		t.Errorf("expected N to go back to group 4, got: group %d, file %d", m.currentGroup, m.currentFile)
+	}
+
+	// Esc goes back to the overview, and again clears the filter.
+	m = update(m, tea.KeyMsg{Type: tea.KeyEsc}, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.filter.active() || len(m.groupOrder()) != 5 {
+		t.Errorf("expected the filter to be cleared, got: %q", m.filter.text)
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// journal is an append-only log of every action that modified files, one
+// JSON encoded actionRecord per line. Records are grouped in batches, one
+// per confirmed TUI action or -apply run, which undo restores as a whole.
+type journal struct {
+	path  string
+	batch string
+}
+
+// defaultJournalPath returns $XDG_STATE_HOME/dugo/journal.jsonl, falling
+// back to ~/.local/state when XDG_STATE_HOME is unset.
+func defaultJournalPath() string {
+	stateHome := os.Getenv("XDG_STATE_HOME")
+	if stateHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		stateHome = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(stateHome, "dugo", "journal.jsonl")
+}
+
+func openJournal(path string) (*journal, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	j := &journal{path: path}
+	j.startBatch()
+	return j, nil
+}
+
+// startBatch makes the records appended from now on part of a new batch.
+func (j *journal) startBatch() {
+	j.batch = time.Now().Format("20060102T150405.000000000")
+}
+
+// append writes rec to the journal as part of the current batch.
+func (j *journal) append(rec actionRecord) error {
+	rec.Batch = j.batch
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readJournal(path string) ([]actionRecord, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []actionRecord
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec actionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("invalid journal %s: %w", path, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// undoJournal restores the files changed by batch, or by the most recent
+// batch that has not been undone yet if batch is empty. Each restored file
+// is recorded in the journal with the "undo" action, so it is not restored
+// twice. Reflinked files were never replaced, so they are skipped. It returns
+// the number of files restored.
+func undoJournal(path, batch string) (int, error) {
+	records, err := readJournal(path)
+	if err != nil {
+		return 0, err
+	}
+
+	undone := make(map[string]bool)
+	for _, rec := range records {
+		if rec.Action == "undo" {
+			undone[rec.Batch+"\x00"+rec.Path] = true
+		}
+	}
+
+	if batch == "" {
+		for i := len(records) - 1; i >= 0; i-- {
+			rec := records[i]
+			if rec.Action != "undo" && rec.Action != "reflink" && !undone[rec.Batch+"\x00"+rec.Path] {
+				batch = rec.Batch
+				break
+			}
+		}
+		if batch == "" {
+			return 0, errors.New("nothing to undo")
+		}
+	} else if !slices.ContainsFunc(records, func(rec actionRecord) bool { return rec.Batch == batch }) {
+		return 0, fmt.Errorf("no batch %s in %s", batch, path)
+	}
+
+	j := &journal{path: path, batch: batch}
+	restored := 0
+	var errs []error
+	for _, rec := range records {
+		if rec.Batch != batch || rec.Action == "undo" || rec.Action == "reflink" || undone[rec.Batch+"\x00"+rec.Path] {
+			continue
+		}
+		if err := undoAction(rec); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		restored++
+		if err := j.append(actionRecord{Time: time.Now(), Action: "undo", Path: rec.Path, Kept: rec.Kept, Size: rec.Size, Hash: rec.Hash}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return restored, errors.Join(errs...)
+}
+
+// undoAction restores the file rec describes. Trashed and quarantined files
+// are moved back. Deleted and linked files are recreated from the kept copy,
+// after checking it still has the recorded content, with their original
+// permissions, owner and modification time.
+func undoAction(rec actionRecord) error {
+	switch rec.Action {
+	case "trash", "quarantine":
+		if _, err := os.Lstat(rec.Path); err == nil {
+			return fmt.Errorf("cannot restore %s: file exists", rec.Path)
+		}
+		if err := moveFile(rec.Dest, rec.Path); err != nil {
+			return err
+		}
+		if rec.Action == "trash" {
+			// Drop the .trashinfo file so the trash no longer lists it.
+			trashDir := filepath.Dir(filepath.Dir(rec.Dest))
+			os.Remove(filepath.Join(trashDir, "info", filepath.Base(rec.Dest)+".trashinfo"))
+		}
+		return nil
+	case "delete", "hardlink", "symlink":
+	default:
+		return fmt.Errorf("cannot undo %s of %s", rec.Action, rec.Path)
+	}
+
+	if rec.Kept == "" {
+		return fmt.Errorf("cannot restore %s: no kept copy recorded", rec.Path)
+	}
+	fi, err := os.Stat(rec.Kept)
+	if err != nil {
+		return fmt.Errorf("cannot restore %s: %w", rec.Path, err)
+	}
+	if fi.Size() != rec.Size {
+		return fmt.Errorf("cannot restore %s: %s changed since it was kept", rec.Path, rec.Kept)
+	}
+	if rec.Hash != "" {
+		hash, err := createFileHash(rec.Kept)
+		if err != nil {
+			return fmt.Errorf("cannot restore %s: %w", rec.Path, err)
+		}
+		if hash != rec.Hash {
+			return fmt.Errorf("cannot restore %s: %s changed since it was kept", rec.Path, rec.Kept)
+		}
+	}
+
+	if rec.Action == "delete" {
+		if _, err := os.Lstat(rec.Path); err == nil {
+			return fmt.Errorf("cannot restore %s: file exists", rec.Path)
+		}
+	} else if err := checkOwnLink(rec); err != nil {
+		return err
+	}
+	err = replaceWith(rec.Path, func(tmp string) error {
+		if err := copyFile(rec.Kept, tmp); err != nil {
+			return err
+		}
+		return restoreMetadata(tmp, rec)
+	})
+	if err != nil {
+		return fmt.Errorf("cannot restore %s: %w", rec.Path, err)
+	}
+	return nil
+}
+
+// checkOwnLink makes sure rec.Path is still the link the action made: a
+// symlink to rec.Kept, or a hardlink sharing its inode. Anything else was
+// written there since, and must not be overwritten. A missing path is fine.
+func checkOwnLink(rec actionRecord) error {
+	fi, err := os.Lstat(rec.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot restore %s: %w", rec.Path, err)
+	}
+	exists := fmt.Errorf("cannot restore %s: file exists", rec.Path)
+	switch rec.Action {
+	case "symlink":
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return exists
+		}
+		target, err := os.Readlink(rec.Path)
+		if err != nil {
+			return fmt.Errorf("cannot restore %s: %w", rec.Path, err)
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(rec.Path), target)
+		}
+		if filepath.Clean(target) != filepath.Clean(rec.Kept) {
+			return exists
+		}
+	case "hardlink":
+		keepInfo, err := os.Lstat(rec.Kept)
+		if err != nil || !os.SameFile(fi, keepInfo) {
+			return exists
+		}
+	}
+	return nil
+}
+
+// restoreMetadata applies the permissions, owner and modification time rec
+// recorded to path. Changing the owner is best effort since it usually needs
+// privileges.
+func restoreMetadata(path string, rec actionRecord) error {
+	if rec.Mode != 0 {
+		if err := os.Chmod(path, rec.Mode.Perm()); err != nil {
+			return err
+		}
+	}
+	if rec.UID >= 0 && rec.GID >= 0 {
+		if fi, err := os.Lstat(path); err == nil {
+			if sys := statSys(fi); sys.uid != rec.UID || sys.gid != rec.GID {
+				_ = os.Lchown(path, rec.UID, rec.GID)
+			}
+		}
+	}
+	if !rec.Mtime.IsZero() {
+		return os.Chtimes(path, rec.Mtime, rec.Mtime)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUndoJournal(t *testing.T) {
+	origVolatileDirs := volatileDirs
+	defer func() { volatileDirs = origVolatileDirs }()
+	volatileDirs = func() []string { return nil }
+
+	tempDir := t.TempDir()
+	quarantineDir := filepath.Join(tempDir, "quarantine")
+	keep := filepath.Join(tempDir, "keep.txt")
+	deleted := filepath.Join(tempDir, "deleted.txt")
+	linked := filepath.Join(tempDir, "linked.txt")
+	quarantined := filepath.Join(tempDir, "quarantined.txt")
+
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, path := range []string{keep, deleted, linked, quarantined} {
+		if err := os.WriteFile(path, []byte("same content"), 0600); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g, err := newDupGroup(int64(len("same content")), "", []string{keep, deleted, linked, quarantined})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.hash, err = createFileHash(keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := groupTargets(g, 0)
+
+	j, err := openJournal(filepath.Join(tempDir, "state", "journal.jsonl"))
+	if err != nil {
+		t.Fatalf("openJournal failed: %v", err)
+	}
+	opts := actionOptions{quarantineDir: quarantineDir, journal: j}
+
+	// The first batch quarantines a file, the second deletes and symlinks.
+	if rec := performAction("quarantine", targets[2], opts, true); rec.Error != "" {
+		t.Fatalf("quarantine failed: %s", rec.Error)
+	}
+	firstBatch := j.batch
+	time.Sleep(time.Millisecond)
+	j.startBatch()
+	if rec := performAction("delete", targets[0], opts, true); rec.Error != "" {
+		t.Fatalf("delete failed: %s", rec.Error)
+	}
+	if rec := performAction("symlink", targets[1], opts, true); rec.Error != "" {
+		t.Fatalf("symlink failed: %s", rec.Error)
+	}
+	// Dry runs are not journaled.
+	performAction("delete", targets[2], opts, false)
+
+	records, err := readJournal(j.path)
+	if err != nil || len(records) != 3 {
+		t.Fatalf("expected 3 journal records, got: %+v, %v", records, err)
+	}
+
+	n, err := undoJournal(j.path, "")
+	if n != 2 || err != nil {
+		t.Fatalf("expected 2 files restored, got: %d, %v", n, err)
+	}
+	for _, path := range []string{deleted, linked} {
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatalf("expected %s to be restored, got: %v", path, err)
+		}
+		if !fi.Mode().IsRegular() || fi.Mode().Perm() != 0600 {
+			t.Errorf("expected %s to be a regular file with mode 0600, got: %v", path, fi.Mode())
+		}
+		if !fi.ModTime().Equal(mtime) {
+			t.Errorf("expected %s to have mtime %v, got: %v", path, mtime, fi.ModTime())
+		}
+	}
+	assertFileExists(t, quarantined, false)
+
+	// The next undo goes back to the quarantine batch, then nothing is left.
+	n, err = undoJournal(j.path, "")
+	if n != 1 || err != nil {
+		t.Fatalf("expected 1 file restored, got: %d, %v", n, err)
+	}
+	b, err := os.ReadFile(quarantined)
+	if err != nil || string(b) != "same content" {
+		t.Errorf("expected %s to be restored, got: %q, %v", quarantined, b, err)
+	}
+	if n, err := restoreQuarantine(quarantineDir); n != 1 || err != nil {
+		t.Errorf("expected the undone entry to be dropped from the manifest, got: %d, %v", n, err)
+	}
+
+	if _, err := undoJournal(j.path, ""); err == nil {
+		t.Error("expected an error with nothing left to undo, got nil")
+	}
+	if n, _ := undoJournal(j.path, firstBatch); n != 0 {
+		t.Errorf("expected an undone batch not to be restored twice, got: %d files", n)
+	}
+	if _, err := undoJournal(j.path, "bogus-batch"); err == nil {
+		t.Error("expected an error for an unknown batch, got nil")
+	}
+}
+
+func TestUndoJournalReflink(t *testing.T) {
+	tempDir := t.TempDir()
+	j, err := openJournal(filepath.Join(tempDir, "journal.jsonl"))
+	if err != nil {
+		t.Fatalf("openJournal failed: %v", err)
+	}
+	rec := actionRecord{Time: time.Now(), Action: "reflink", Path: filepath.Join(tempDir, "a"), Kept: filepath.Join(tempDir, "b")}
+	if err := j.append(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := undoJournal(j.path, ""); err == nil {
+		t.Error("expected nothing to undo after a reflink, got nil")
+	}
+	if n, err := undoJournal(j.path, j.batch); n != 0 || err != nil {
+		t.Errorf("expected the reflink to be skipped, got: %d, %v", n, err)
+	}
+	if records, err := readJournal(j.path); err != nil || len(records) != 1 {
+		t.Errorf("expected no undo record, got: %+v, %v", records, err)
+	}
+}
+
+func TestUndoActionKeptChanged(t *testing.T) {
+	tempDir := t.TempDir()
+	keep := filepath.Join(tempDir, "keep.txt")
+	path := filepath.Join(tempDir, "deleted.txt")
+	if err := os.WriteFile(keep, []byte("changed content"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	rec := actionRecord{Action: "delete", Path: path, Kept: keep, Size: int64(len("same content")), UID: -1, GID: -1}
+	if err := undoAction(rec); err == nil {
+		t.Error("expected an error restoring from a changed file, got nil")
+	}
+	assertFileExists(t, path, false)
+}
+
+func TestUndoActionLinkReplaced(t *testing.T) {
+	tempDir := t.TempDir()
+	keep := filepath.Join(tempDir, "keep.txt")
+	writeTestFile(t, keep, "same content")
+
+	tests := []struct {
+		name    string
+		action  string
+		replace func(path string) error
+	}{
+		{
+			name:    "Hardlink replaced by a new file",
+			action:  "hardlink",
+			replace: func(path string) error { return os.WriteFile(path, []byte("new content"), 0644) },
+		},
+		{
+			name:    "Symlink replaced by a new file",
+			action:  "symlink",
+			replace: func(path string) error { return os.WriteFile(path, []byte("new content"), 0644) },
+		},
+		{
+			name:    "Symlink pointing elsewhere",
+			action:  "symlink",
+			replace: func(path string) error { return os.Symlink(filepath.Join(tempDir, "other.txt"), path) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, strings.ReplaceAll(tt.name, " ", "_"))
+			if err := tt.replace(path); err != nil {
+				t.Fatal(err)
+			}
+			rec := actionRecord{Action: tt.action, Path: path, Kept: keep, Size: int64(len("same content")), UID: -1, GID: -1}
+			err := undoAction(rec)
+			if err == nil || !strings.Contains(err.Error(), "file exists") {
+				t.Errorf("expected a file exists error, got: %v", err)
+			}
+			if fi, err := os.Lstat(path); err != nil || (tt.action == "hardlink" && fi.Size() != int64(len("new content"))) {
+				t.Errorf("expected %s to be left alone, got: %v", path, err)
+			}
+		})
+	}
+
+	// The links dugo made are restored.
+	for _, action := range []string{"hardlink", "symlink"} {
+		path := filepath.Join(tempDir, action+".txt")
+		link := os.Link
+		if action == "symlink" {
+			link = os.Symlink
+		}
+		if err := link(keep, path); err != nil {
+			t.Fatal(err)
+		}
+		rec := actionRecord{Action: action, Path: path, Kept: keep, Size: int64(len("same content")), UID: -1, GID: -1}
+		if err := undoAction(rec); err != nil {
+			t.Errorf("expected the %s to be restored, got: %v", action, err)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// keepRule compares two files of a duplicate group and returns a negative
+// number if a should rather be kept than b, a positive number if b should,
+// and zero if the rule has no preference.
+type keepRule func(a, b dupFile) int
+
+// keepRules are applied in order, each later rule breaking ties left by the
+// earlier ones.
+type keepRules []keepRule
+
+// parseKeepRule parses a rule given to -keep. roots are the scanned
+// directories in the order they were given, used by the "first" rule.
+func parseKeepRule(s string, roots []string) (keepRule, error) {
+	name, arg, hasArg := strings.Cut(s, "=")
+	switch {
+	case s == "oldest":
+		return func(a, b dupFile) int { return a.mtime.Compare(b.mtime) }, nil
+	case s == "newest":
+		return func(a, b dupFile) int { return b.mtime.Compare(a.mtime) }, nil
+	case s == "shortest":
+		return func(a, b dupFile) int { return len(a.path) - len(b.path) }, nil
+	case s == "longest":
+		return func(a, b dupFile) int { return len(b.path) - len(a.path) }, nil
+	case s == "first":
+		return func(a, b dupFile) int { return rootIndex(a.path, roots) - rootIndex(b.path, roots) }, nil
+	case name == "path" && hasArg:
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keep rule %q: %w", s, err)
+		}
+		return preferIf(func(f dupFile) bool { return re.MatchString(f.path) }), nil
+	case name == "dir" && hasArg:
+		dir, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid keep rule %q: %w", s, err)
+		}
+		return preferIf(func(f dupFile) bool { return isInside(f.path, dir) }), nil
+	default:
+		return nil, fmt.Errorf("unknown keep rule %q", s)
+	}
+}
+
+// preferIf returns a rule that prefers files for which match is true.
+func preferIf(match func(dupFile) bool) keepRule {
+	return func(a, b dupFile) int {
+		ma, mb := match(a), match(b)
+		switch {
+		case ma && !mb:
+			return -1
+		case mb && !ma:
+			return 1
+		default:
+			return 0
+		}
+	}
+}
+
+// rootIndex returns the index of the first root containing path, or
+// len(roots) if none does.
+func rootIndex(path string, roots []string) int {
+	for i, root := range roots {
+		if isInside(path, root) {
+			return i
+		}
+	}
+	return len(roots)
+}
+
+// isInside reports whether path is dir or inside it.
+func isInside(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// choose returns the index of the file of g that should be kept: the best
+// file according to the rules, and the first such file when they tie.
+func (rules keepRules) choose(g dupGroup) int {
+	best := 0
+	for i := 1; i < len(g.files); i++ {
+		if rules.compare(g.files[i], g.files[best]) < 0 {
+			best = i
+		}
+	}
+	return best
+}
+
+func (rules keepRules) compare(a, b dupFile) int {
+	for _, rule := range rules {
+		if c := rule(a, b); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// keepFlag collects repeated -keep flags.
+type keepFlag []string
+
+func (f *keepFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *keepFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
+// parseKeepRules parses every rule of f.
+func parseKeepRules(f keepFlag, roots []string) (keepRules, error) {
+	rules := make(keepRules, 0, len(f))
+	for _, s := range f {
+		rule, err := parseKeepRule(s, roots)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeepRules(t *testing.T) {
+	roots := []string{filepath.FromSlash("/srv/main"), filepath.FromSlash("/backup")}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	g := dupGroup{
+		size: 10,
+		files: []dupFile{
+			{path: filepath.FromSlash("/backup/2023/report.pdf"), mtime: base.Add(2 * time.Hour)},
+			{path: filepath.FromSlash("/srv/main/docs/report.pdf"), mtime: base.Add(time.Hour)},
+			{path: filepath.FromSlash("/backup/report.pdf"), mtime: base},
+			{path: filepath.FromSlash("/srv/main/a copy.pdf"), mtime: base.Add(time.Hour)},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		rules    []string
+		expected int
+	}{
+		{"no rules keeps the first file", nil, 0},
+		{"oldest", []string{"oldest"}, 2},
+		{"newest", []string{"newest"}, 0},
+		{"shortest", []string{"shortest"}, 2},
+		{"longest", []string{"longest"}, 1},
+		{"first root", []string{"first"}, 1},
+		{"first root then shortest", []string{"first", "shortest"}, 3},
+		{"path regex", []string{"path=copy"}, 3},
+		{"preferred directory", []string{"dir=" + filepath.FromSlash("/backup/2023")}, 0},
+		{"tie broken by later rule", []string{"dir=" + filepath.FromSlash("/srv/main"), "longest"}, 1},
+		{"newest tie keeps earlier file", []string{"path=main", "newest"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := parseKeepRules(tt.rules, roots)
+			if err != nil {
+				t.Fatalf("parseKeepRules failed: %v", err)
+			}
+			if got := rules.choose(g); got != tt.expected {
+				t.Errorf("expected to keep %s, got: %s", g.files[tt.expected].path, g.files[got].path)
+			}
+		})
+	}
+}
+
+func TestParseKeepRuleErrors(t *testing.T) {
+	for _, s := range []string{"", "biggest", "path=(", "dir"} {
+		if _, err := parseKeepRule(s, nil); err == nil {
+			t.Errorf("expected an error for rule %q, got nil", s)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// hardlinkFile replaces dup with a hardlink to keep and returns the number of
+// bytes reclaimed. The link is created under a temporary name next to dup and
+// renamed over it, so dup is never missing. It refuses to link files on
+// different filesystems or whose content no longer matches.
+func hardlinkFile(dup, keep string) (int64, error) {
+	dupInfo, err := os.Lstat(dup)
+	if err != nil {
+		return 0, err
+	}
+	keepInfo, err := os.Lstat(keep)
+	if err != nil {
+		return 0, err
+	}
+	if !dupInfo.Mode().IsRegular() || !keepInfo.Mode().IsRegular() {
+		return 0, fmt.Errorf("cannot link %s to %s: not regular files", dup, keep)
+	}
+	if os.SameFile(dupInfo, keepInfo) {
+		return 0, nil
+	}
+	if statSys(dupInfo).dev != statSys(keepInfo).dev {
+		return 0, fmt.Errorf("cannot link %s to %s: files are on different filesystems", dup, keep)
+	}
+
+	eq, err := filesAreEqual(dup, keep)
+	if err != nil {
+		return 0, err
+	}
+	if !eq {
+		return 0, fmt.Errorf("cannot link %s to %s: content differs", dup, keep)
+	}
+
+	if err := replaceWith(dup, func(tmp string) error { return os.Link(keep, tmp) }); err != nil {
+		return 0, err
+	}
+	return dupInfo.Size(), nil
+}
+
+// symlinkFile replaces dup with a symbolic link to keep and returns the
+// number of bytes reclaimed. With relative set the link target is relative to
+// the directory of dup. Like hardlinkFile it re-compares the content first and
+// replaces dup atomically. It also refuses to point links into directories
+// that are routinely cleaned up, where keep might disappear.
+func symlinkFile(dup, keep string, relative bool) (int64, error) {
+	dupInfo, err := os.Lstat(dup)
+	if err != nil {
+		return 0, err
+	}
+	keepInfo, err := os.Lstat(keep)
+	if err != nil {
+		return 0, err
+	}
+	if !dupInfo.Mode().IsRegular() || !keepInfo.Mode().IsRegular() {
+		return 0, fmt.Errorf("cannot link %s to %s: not regular files", dup, keep)
+	}
+	if os.SameFile(dupInfo, keepInfo) {
+		return 0, fmt.Errorf("cannot link %s to %s: they are the same file", dup, keep)
+	}
+	if dir, ok := inVolatileDir(keep); ok {
+		return 0, fmt.Errorf("cannot link %s to %s: %s may be cleaned up", dup, keep, dir)
+	}
+
+	eq, err := filesAreEqual(dup, keep)
+	if err != nil {
+		return 0, err
+	}
+	if !eq {
+		return 0, fmt.Errorf("cannot link %s to %s: content differs", dup, keep)
+	}
+
+	target, err := symlinkTarget(dup, keep, relative)
+	if err != nil {
+		return 0, err
+	}
+	if err := replaceWith(dup, func(tmp string) error { return os.Symlink(target, tmp) }); err != nil {
+		return 0, err
+	}
+	return dupInfo.Size(), nil
+}
+
+// symlinkTarget returns the target a symlink at dup pointing to keep should
+// have.
+func symlinkTarget(dup, keep string, relative bool) (string, error) {
+	keep, err := filepath.Abs(keep)
+	if err != nil {
+		return "", err
+	}
+	if !relative {
+		return keep, nil
+	}
+	dup, err = filepath.Abs(dup)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Rel(filepath.Dir(dup), keep)
+}
+
+// volatileDirs lists directories whose content the system may remove at any
+// time, so they must not hold the target of a symlink. It is a variable so
+// tests, which work in the temporary directory, can replace it.
+var volatileDirs = func() []string {
+	return []string{os.TempDir(), "/tmp", "/var/tmp", "/dev/shm", "/run"}
+}
+
+// inVolatileDir reports whether path is inside one of volatileDirs, and which.
+func inVolatileDir(path string) (string, bool) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	for _, dir := range volatileDirs() {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+		if isInside(path, dir) {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
+// checkSymlinkTarget refuses keep as the target of symlinks if it may
+// disappear: if it is in a directory the system cleans up, or in one that
+// files were removed from during this run, as when a directory is being
+// emptied of its duplicates.
+func checkSymlinkTarget(keep string, removedFrom map[string]struct{}) error {
+	if dir, ok := inVolatileDir(keep); ok {
+		return fmt.Errorf("%s may be cleaned up", dir)
+	}
+	for dir := range removedFrom {
+		if isInside(keep, dir) {
+			return fmt.Errorf("files were removed from %s in this run", dir)
+		}
+	}
+	return nil
+}
+
+// replaceWith atomically replaces path with the file create makes at a
+// temporary path in the same directory.
+func replaceWith(path string, create func(tmp string) error) error {
+	dir, base := filepath.Split(path)
+	for range 10 {
+		tmp := filepath.Join(dir, "."+base+".dugo-"+strconv.FormatUint(rand.Uint64(), 36))
+		err := create(tmp)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot replace %s: no free temporary name", path)
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHardlinkFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	keep := filepath.Join(tempDir, "keep.txt")
+	dup := filepath.Join(tempDir, "dup.txt")
+	other := filepath.Join(tempDir, "other.txt")
+	files := map[string]string{
+		keep:  "same content",
+		dup:   "same content",
+		other: "different!!!",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	if _, err := hardlinkFile(other, keep); err == nil {
+		t.Error("expected an error linking files with different content, got nil")
+	}
+
+	n, err := hardlinkFile(dup, keep)
+	if err != nil {
+		t.Fatalf("hardlinkFile failed: %v", err)
+	}
+	if n != int64(len("same content")) {
+		t.Errorf("expected %d bytes reclaimed, got: %d", len("same content"), n)
+	}
+
+	keepInfo, err1 := os.Stat(keep)
+	dupInfo, err2 := os.Stat(dup)
+	if err1 != nil || err2 != nil || !os.SameFile(keepInfo, dupInfo) {
+		t.Errorf("%s is not a hardlink to %s", dup, keep)
+	}
+
+	n, err = hardlinkFile(dup, keep)
+	if err != nil || n != 0 {
+		t.Errorf("linking an existing hardlink: expected 0 bytes and no error, got: %d, %v", n, err)
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected 3 files in %s, got: %d", tempDir, len(entries))
+	}
+}
+
+func TestReplaceWith(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "file.txt")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	err := replaceWith(path, func(tmp string) error {
+		return os.WriteFile(tmp, []byte("new"), 0644)
+	})
+	if err != nil {
+		t.Fatalf("replaceWith failed: %v", err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil || string(b) != "new" {
+		t.Errorf("expected content %q, got: %q (%v)", "new", b, err)
+	}
+}
+
+func TestSymlinkFile(t *testing.T) {
+	origVolatileDirs := volatileDirs
+	defer func() { volatileDirs = origVolatileDirs }()
+
+	tests := []struct {
+		name     string
+		relative bool
+	}{
+		{"absolute", false},
+		{"relative", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			volatileDirs = func() []string { return nil }
+
+			tempDir := t.TempDir()
+			if err := os.Mkdir(filepath.Join(tempDir, "sub"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			keep := filepath.Join(tempDir, "keep.txt")
+			dup := filepath.Join(tempDir, "sub", "dup.txt")
+			for _, path := range []string{keep, dup} {
+				if err := os.WriteFile(path, []byte("same content"), 0644); err != nil {
+					t.Fatalf("Failed to create test file: %v", err)
+				}
+			}
+
+			n, err := symlinkFile(dup, keep, tt.relative)
+			if err != nil {
+				t.Fatalf("symlinkFile failed: %v", err)
+			}
+			if n != int64(len("same content")) {
+				t.Errorf("expected %d bytes reclaimed, got: %d", len("same content"), n)
+			}
+
+			target, err := os.Readlink(dup)
+			if err != nil {
+				t.Fatalf("%s is not a symlink: %v", dup, err)
+			}
+			if filepath.IsAbs(target) == tt.relative {
+				t.Errorf("unexpected symlink target %q", target)
+			}
+			b, err := os.ReadFile(dup)
+			if err != nil || string(b) != "same content" {
+				t.Errorf("symlink does not resolve to the kept file: %q, %v", b, err)
+			}
+
+			if _, err := symlinkFile(dup, keep, tt.relative); err == nil {
+				t.Error("expected an error replacing a symlink, got nil")
+			}
+		})
+	}
+}
+
+func TestSymlinkFileVolatileDir(t *testing.T) {
+	tempDir := t.TempDir()
+
+	origVolatileDirs := volatileDirs
+	defer func() { volatileDirs = origVolatileDirs }()
+	volatileDirs = func() []string { return []string{tempDir} }
+
+	keep := filepath.Join(tempDir, "keep.txt")
+	dup := filepath.Join(tempDir, "dup.txt")
+	for _, path := range []string{keep, dup} {
+		if err := os.WriteFile(path, []byte("same content"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	if _, err := symlinkFile(dup, keep, false); err == nil {
+		t.Error("expected an error linking to a file in a volatile directory, got nil")
+	}
+	if _, err := os.Readlink(dup); err == nil {
+		t.Errorf("%s was replaced by a symlink", dup)
+	}
+}
+
+func TestSymlinkTarget(t *testing.T) {
+	tests := []struct {
+		dup      string
+		keep     string
+		relative bool
+		expected string
+	}{
+		{"/data/a/dup.txt", "/data/keep.txt", false, "/data/keep.txt"},
+		{"/data/a/dup.txt", "/data/keep.txt", true, "../keep.txt"},
+		{"/data/dup.txt", "/data/b/keep.txt", true, "b/keep.txt"},
+	}
+	for _, tt := range tests {
+		got, err := symlinkTarget(filepath.FromSlash(tt.dup), filepath.FromSlash(tt.keep), tt.relative)
+		if err != nil {
+			t.Errorf("symlinkTarget(%q, %q) failed: %v", tt.dup, tt.keep, err)
+			continue
+		}
+		if got != filepath.FromSlash(tt.expected) {
+			t.Errorf("symlinkTarget(%q, %q): expected %q, got %q", tt.dup, tt.keep, tt.expected, got)
+		}
+	}
+}
+
+func TestCheckSymlinkTarget(t *testing.T) {
+	origVolatileDirs := volatileDirs
+	defer func() { volatileDirs = origVolatileDirs }()
+	volatileDirs = func() []string { return nil }
+
+	removedFrom := map[string]struct{}{filepath.FromSlash("/data/backup"): {}}
+	tests := []struct {
+		keep    string
+		wantErr bool
+	}{
+		{keep: "/data/main/a.txt", wantErr: false},
+		{keep: "/data/backup/a.txt", wantErr: true},
+		{keep: "/data/backup/old/a.txt", wantErr: true},
+		{keep: "/data/backup2/a.txt", wantErr: false},
+	}
+	for _, tt := range tests {
+		if err := checkSymlinkTarget(filepath.FromSlash(tt.keep), removedFrom); (err != nil) != tt.wantErr {
+			t.Errorf("checkSymlinkTarget(%q): expected error: %v, got: %v", tt.keep, tt.wantErr, err)
+		}
+	}
+}
+
+func TestSymlinkIntoEmptiedDir(t *testing.T) {
+	origVolatileDirs := volatileDirs
+	defer func() { volatileDirs = origVolatileDirs }()
+	volatileDirs = func() []string { return nil }
+
+	tempDir := t.TempDir()
+	backup := filepath.Join(tempDir, "backup")
+	if err := os.MkdirAll(backup, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, path := range []string{filepath.Join(tempDir, "a.txt"), filepath.Join(backup, "a.txt"), filepath.Join(backup, "b.txt"), filepath.Join(tempDir, "b.txt")} {
+		if err := os.WriteFile(path, []byte("same content"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	g1, err := newDupGroup(int64(len("same content")), "", paths[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	g2, err := newDupGroup(int64(len("same content")), "", paths[2:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleting backup/a.txt marks backup as being emptied, so b.txt must not
+	// become a symlink to backup/b.txt.
+	opts := actionOptions{removedFrom: make(map[string]struct{})}
+	if rec := performAction("delete", groupTargets(g1, 0)[0], opts, true); rec.Error != "" {
+		t.Fatalf("delete failed: %s", rec.Error)
+	}
+	rec := performAction("symlink", groupTargets(g2, 0)[0], opts, true)
+	if !strings.Contains(rec.Error, "removed from "+backup) {
+		t.Errorf("expected the symlink into %s to be refused, got: %q", backup, rec.Error)
+	}
+	if _, err := os.Readlink(paths[3]); err == nil {
+		t.Errorf("%s was replaced by a symlink", paths[3])
+	}
+}
@@ -2,31 +2,79 @@ package main
 
 import (
 	"flag"
-	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
-	"sync"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// Exit codes returned by dugo, so scripts can branch on the result without
+// parsing its output.
+const (
+	exitNoDuplicates = 0 // the scan completed and found no duplicates
+	exitDuplicates   = 1 // the scan completed and found duplicates
+	exitUsage        = 2 // invalid flags or arguments
+	exitPartial      = 3 // some files could not be read or acted upon
+	exitFailure      = 4 // the scan could not be completed
+)
+
 func main() {
-	var ignoreNamesFlag, ignoreRegexFlag string
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "restore", "purge":
+			os.Exit(runQuarantineCommand(os.Args[1], os.Args[2:]))
+		case "undo":
+			os.Exit(runUndoCommand(os.Args[2:]))
+		}
+	}
+
+	var ignoreNamesFlag, ignoreRegexFlag, format, groupSepFlag, templateFlag, outputPath, actionFlag, quarantineDir, journalPath string
+	var keep keepFlag
 	var workers uint
-	var interactiveMode bool
+	var interactiveMode, promptMode, applyMode, execute, print0, relativeSymlinks, trash, verifyContent bool
 	flag.StringVar(&ignoreNamesFlag, "ignore-names", "", "Comma-separated list of file/folder names to ignore (exact match)")
 	flag.StringVar(&ignoreRegexFlag, "ignore-regex", "", "Regex pattern to ignore files by path")
 	flag.BoolVar(&interactiveMode, "it", false, "Interactive TUI mode")
+	flag.BoolVar(&promptMode, "prompt", false, "Interactive line-based mode asking which files to remove, for terminals that cannot run the TUI")
+	flag.BoolVar(&applyMode, "apply", false, "Apply -action to every duplicate not kept by the -keep rules (dry run unless -execute)")
+	flag.BoolVar(&execute, "execute", false, "Make -apply modify files instead of only reporting what it would do")
+	flag.StringVar(&format, "format", "default", "Output format: default, fdupes, csv, print0, json, html or sh")
+	flag.StringVar(&format, "report", "default", "Alias for -format")
+	flag.StringVar(&outputPath, "o", "", "Write the output to this file instead of stdout")
+	flag.BoolVar(&print0, "print0", false, "Write NUL-terminated paths (same as -format=print0)")
+	flag.StringVar(&groupSepFlag, "group-sep", "", "Group separator for -print0, Go escapes allowed (default NUL)")
+	flag.StringVar(&actionFlag, "action", "delete", "Action applied to duplicates by -apply and -format=sh: delete, trash, quarantine, hardlink, symlink or reflink")
+	flag.BoolVar(&relativeSymlinks, "relative-symlinks", false, "Create symlinks with targets relative to the link's directory")
+	flag.StringVar(&quarantineDir, "quarantine", "", "Move removed duplicates into this directory instead of deleting them")
+	flag.BoolVar(&trash, "trash", false, "Move removed duplicates to the trash instead of deleting them")
+	flag.BoolVar(&verifyContent, "verify-content", false, "Compare the content of files again right before acting on them, not only their size, mtime and inode")
+	flag.StringVar(&journalPath, "journal", defaultJournalPath(), "Record the actions that modify files in this file so they can be undone; empty disables it")
+	flag.Var(&keep, "keep", "Rule choosing the file of each group to keep: oldest, newest, shortest, longest, first, path=<regex> or dir=<dir>; repeat to break ties")
+	flag.StringVar(&templateFlag, "template", "", "Go text/template, or a file containing one, executed for each group")
 	flag.UintVar(&workers, "workers", 4, "Number of concurrent workers")
 	flag.Parse()
 
+	formatSet := false
+	flag.Visit(func(f *flag.Flag) {
+		formatSet = formatSet || f.Name == "format" || f.Name == "report"
+	})
+
 	if flag.NArg() < 1 {
-		log.Fatalf("Usage: %s [options] <dir-path>", filepath.Base(os.Args[0]))
+		exitf(exitUsage, "Usage: %s [options] <dir-path>...", filepath.Base(os.Args[0]))
+	}
+	roots := make([]string, flag.NArg())
+	for i, arg := range flag.Args() {
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			exitf(exitUsage, "Invalid directory %q: %v", arg, err)
+		}
+		roots[i] = abs
 	}
-	path := flag.Arg(0)
 
 	ignoreNames := map[string]struct{}{}
 	if ignoreNamesFlag != "" {
@@ -40,70 +88,251 @@ func main() {
 		var err error
 		ignoreRegex, err = regexp.Compile(ignoreRegexFlag)
 		if err != nil {
-			log.Fatalf("Invalid ignore regex: %v", err)
+			exitf(exitUsage, "Invalid ignore regex: %v", err)
+		}
+	}
+
+	groupSep, err := strconv.Unquote(`"` + groupSepFlag + `"`)
+	if err != nil {
+		exitf(exitUsage, "Invalid group separator: %q", groupSepFlag)
+	}
+
+	var tmpl string
+	if templateFlag != "" {
+		tmpl, err = readTemplate(templateFlag)
+		if err != nil {
+			exitf(exitUsage, "Invalid template: %v", err)
 		}
 	}
 
-	abs, err := filepath.Abs(path)
+	format, err = outputFormat(format, formatSet, print0, templateFlag != "")
 	if err != nil {
-		log.Fatal(err)
+		exitf(exitUsage, "%v", err)
+	}
+	// The interactive modes write no report.
+	if outputPath != "" && (interactiveMode || promptMode) {
+		exitf(exitUsage, "-o cannot be combined with -it or -prompt")
+	}
+	out := &outputFile{path: outputPath}
+	if quarantineDir != "" && trash {
+		exitf(exitUsage, "-quarantine and -trash cannot be combined")
+	}
+	if quarantineDir != "" {
+		quarantineDir, err = filepath.Abs(quarantineDir)
+		if err == nil {
+			err = os.MkdirAll(quarantineDir, 0o755)
+		}
+		if err != nil {
+			exitf(exitUsage, "Invalid quarantine directory: %v", err)
+		}
+		// Quarantined files would be found as duplicates by the next scan.
+		for _, root := range roots {
+			if isInside(quarantineDir, root) {
+				exitf(exitUsage, "The quarantine directory %s must not be inside %s", quarantineDir, root)
+			}
+		}
 	}
 
-	m, err := scanDir(abs, ignoreNames, ignoreRegex)
+	keepRules, err := parseKeepRules(keep, roots)
 	if err != nil {
-		log.Fatal(err)
+		exitf(exitUsage, "%v", err)
 	}
 
-	sem := make(chan struct{}, workers)
-	results := make(chan []string)
+	actionOpts := actionOptions{
+		relativeSymlinks: relativeSymlinks,
+		quarantineDir:    quarantineDir,
+		roots:            roots,
+		trash:            trash,
+		keep:             keepRules,
+		verifyContent:    verifyContent,
+		removedFrom:      make(map[string]struct{}),
+	}
 
-	go func() {
-		var wg sync.WaitGroup
-		for _, v := range m {
-			if len(v) < 2 {
-				continue
-			}
-			wg.Add(1)
-			go func(files sameSizeFiles) {
-				defer wg.Done()
-				sem <- struct{}{}
-				defer func() { <-sem }()
-
-				res, err := groupByHash(files, workers)
-				if err != nil {
-					log.Printf("Error: %v", err)
-					return
-				}
-
-				for _, v := range res {
-					groups, err := partitionIntoEqualGroups(v)
-					if err != nil {
-						log.Printf("Error: %v", err)
-						continue
-					}
-					for _, group := range groups {
-						if len(group) >= 2 {
-							results <- group
-						}
-					}
-				}
-			}(v)
-		}
-
-		go func() {
-			wg.Wait()
-			close(results)
-		}()
-	}()
+	if interactiveMode && promptMode {
+		exitf(exitUsage, "-it and -prompt cannot be combined")
+	}
+	if journalPath != "" && (interactiveMode || promptMode || applyMode && execute) {
+		actionOpts.journal, err = openJournal(journalPath)
+		if err != nil {
+			exitf(exitUsage, "Invalid journal: %v", err)
+		}
+	}
+
+	var r reporter
+	var ar actionReporter
+	action := actionFlag
+	if applyMode {
+		if interactiveMode || promptMode {
+			exitf(exitUsage, "-apply cannot be combined with -it or -prompt")
+		}
+		if len(keepRules) == 0 {
+			exitf(exitUsage, "-apply needs at least one -keep rule")
+		}
+		if action == "delete" {
+			action = actionOpts.removeAction()
+		}
+		if !slices.Contains(actions, action) {
+			exitf(exitUsage, "Unknown action %q", action)
+		}
+		if action == "quarantine" && quarantineDir == "" {
+			exitf(exitUsage, "-action=quarantine needs -quarantine <dir>")
+		}
+		ar, err = newActionReporter(format, out)
+	} else {
+		r, err = newReporter(format, out, reportOptions{
+			groupSep:   groupSep,
+			template:   tmpl,
+			action:     actionFlag,
+			actionOpts: actionOpts,
+		})
+	}
+	if err != nil {
+		exitf(exitUsage, "%v", err)
+	}
 
+	var stats scanStats
 	if interactiveMode {
-		p := tea.NewProgram(initialModel(results))
-		if _, err := p.Run(); err != nil {
-			log.Fatal(err)
+		os.Exit(runInteractive(roots, ignoreNames, ignoreRegex, workers, actionOpts, &stats))
+	}
+
+	if err := out.open(); err != nil {
+		exitf(exitUsage, "%v", err)
+	}
+	m, err := scanDirs(roots, ignoreNames, ignoreRegex, &stats)
+	if err != nil {
+		exitf(exitFailure, "%v", err)
+	}
+
+	results := findDuplicates(m, workers, &stats)
+
+	switch {
+	case promptMode:
+		if failed := handleDeletions(results, actionOpts); failed > 0 {
+			log.Printf("%d removals failed", failed)
+			os.Exit(exitPartial)
+		}
+	case applyMode:
+		failed, err := applyActions(results, action, actionOpts, execute, ar)
+		if err != nil {
+			exitf(exitFailure, "%v", err)
+		}
+		if err := out.Close(); err != nil {
+			exitf(exitFailure, "%v", err)
+		}
+		if failed > 0 {
+			log.Printf("%d actions failed", failed)
+			os.Exit(exitPartial)
+		}
+	default:
+		if err := report(r, results); err != nil {
+			exitf(exitFailure, "%v", err)
+		}
+		if err := out.Close(); err != nil {
+			exitf(exitFailure, "%v", err)
+		}
+	}
+
+	os.Exit(stats.exitCode())
+}
+
+// outputFile is where reports are written: the file named by -o, or stdout
+// if path is empty. It is only created by open, once every flag has been
+// checked, so that a usage error leaves an existing file untouched.
+type outputFile struct {
+	path string
+	*os.File
+}
+
+func (o *outputFile) open() (err error) {
+	if o.path == "" {
+		o.File = os.Stdout
+		return nil
+	}
+	o.File, err = os.Create(o.path)
+	return err
+}
+
+// runInteractive runs the TUI while the roots are walked and their
+// duplicates found in the background, so that the status bar shows the
+// whole scan. It returns the exit code.
+func runInteractive(roots []string, ignoreNames map[string]struct{}, ignoreRegex *regexp.Regexp, workers uint, actionOpts actionOptions, stats *scanStats) int {
+	results := make(chan dupGroup)
+	p := tea.NewProgram(initialModel(results, stats, actionOpts))
+	go func() {
+		m, err := scanDirs(roots, ignoreNames, ignoreRegex, stats)
+		if err != nil {
+			p.Send(err)
+			return
+		}
+		for g := range findDuplicates(m, workers, stats) {
+			results <- g
 		}
+		close(results)
+	}()
+
+	final, err := p.Run()
+	if err != nil {
+		log.Printf("%v", err)
+		return exitFailure
+	}
+	if err := final.(model).err; err != nil {
+		log.Printf("%v", err)
+		return exitFailure
+	}
+	return stats.exitCode()
+}
+
+// runQuarantineCommand runs "dugo restore <dir>" or "dugo purge <dir>" on a
+// quarantine directory and returns the exit code.
+func runQuarantineCommand(cmd string, args []string) int {
+	if len(args) != 1 {
+		log.Printf("Usage: %s %s <quarantine-dir>", filepath.Base(os.Args[0]), cmd)
+		return exitUsage
+	}
+
+	var n int
+	var err error
+	if cmd == "restore" {
+		n, err = restoreQuarantine(args[0])
+		log.Printf("Restored %d files", n)
 	} else {
-		for group := range results {
-			fmt.Println("Equal files:", group)
+		n, err = purgeQuarantine(args[0])
+		log.Printf("Purged %d files", n)
+	}
+	if err != nil {
+		log.Printf("Error: %v", err)
+		if n > 0 {
+			return exitPartial
 		}
+		return exitFailure
+	}
+	return 0
+}
+
+// runUndoCommand runs "dugo undo [-journal <file>] [batch]", restoring the
+// files changed by a batch of the journal, and returns the exit code.
+func runUndoCommand(args []string) int {
+	fs := flag.NewFlagSet("undo", flag.ContinueOnError)
+	journalPath := fs.String("journal", defaultJournalPath(), "Journal recording the actions to undo")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 1 || *journalPath == "" {
+		log.Printf("Usage: %s undo [-journal <file>] [batch]", filepath.Base(os.Args[0]))
+		return exitUsage
 	}
+
+	n, err := undoJournal(*journalPath, fs.Arg(0))
+	log.Printf("Restored %d files", n)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		if n > 0 {
+			return exitPartial
+		}
+		return exitFailure
+	}
+	return 0
+}
+
+// exitf logs a formatted message and exits with code.
+func exitf(code int, format string, v ...any) {
+	log.Printf(format, v...)
+	os.Exit(code)
 }
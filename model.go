@@ -2,24 +2,70 @@ package main
 
 import (
 	"fmt"
-	"os"
+	"maps"
+	"slices"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 type model struct {
-	groups       [][]string
+	groups       []dupGroup
 	currentGroup int
 	currentFile  int
 	selected     map[int]map[int]struct{}
 	quitting     bool
 	err          error
 	scanning     bool
-	resultsChan  <-chan []string
+	resultsChan  <-chan dupGroup
 	showConfirm  bool
-	toDelete     []string
+	// confirmAction is the action the confirm dialog applies to pending,
+	// either "delete", "hardlink", "symlink" or "reflink".
+	confirmAction string
+	pending       []actionTarget
+	actionOpts    actionOptions
+	// width and height are the terminal size, zero until the first
+	// tea.WindowSizeMsg. offset is the index of the first file of the
+	// current group shown in the scrolling list.
+	width, height int
+	offset        int
+	// overview shows the list of all groups instead of the files of the
+	// current one. sortBy orders that list: "reclaimable", "size" or
+	// "count", largest first. overviewOffset is the first row shown.
+	overview       bool
+	sortBy         string
+	overviewOffset int
+	// preview is the loaded preview of the highlighted file, shown in a
+	// pane beside the file list unless hidePreview is set.
+	preview     filePreview
+	hidePreview bool
+	// filter hides the groups that do not match it. While editingFilter is
+	// set, keys edit filterInput instead, and filterErr explains why the
+	// input was rejected.
+	filter        groupFilter
+	editingFilter bool
+	filterInput   string
+	filterErr     string
+	// scanning stays set until the results channel is closed. stats shows
+	// its progress in the status bar, along with the time since started,
+	// or until finished once it is done.
+	stats             *scanStats
+	started, finished time.Time
+	spinner           int
+	// batch holds the records of the last batch of actions, summarized
+	// with its failures in a panel while showBatch is set. log holds the
+	// records of every action of the session, listed instead of the groups
+	// while showLog is set, from logOffset.
+	batch     []actionRecord
+	showBatch bool
+	// batchPeers maps the path of each file of the last batch to the paths
+	// of the other files of its group, to put it back there on undo.
+	batchPeers map[string][]string
+	log        []actionRecord
+	showLog    bool
+	logOffset  int
 }
 
 type scanCompleteMsg struct{}
@@ -34,14 +80,21 @@ var (
 	selectedStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Bold(true)
 	errorStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
 	helpStyle         = lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Italic(true)
+	matchStyle        = lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Underline(true)
+	previewStyle      = lipgloss.NewStyle().Border(lipgloss.NormalBorder(), false, false, false, true).BorderForeground(lipgloss.Color("240")).PaddingLeft(1)
 )
 
-func initialModel(resultsChan <-chan []string) model {
+func initialModel(resultsChan <-chan dupGroup, stats *scanStats, actionOpts actionOptions) model {
 	return model{
 		resultsChan: resultsChan,
+		stats:       stats,
+		started:     time.Now(),
+		actionOpts:  actionOpts,
 		selected:    make(map[int]map[int]struct{}),
 		scanning:    true,
-		groups:      make([][]string, 0),
+		overview:    true,
+		sortBy:      "reclaimable",
+		groups:      make([]dupGroup, 0),
 	}
 }
 
@@ -49,19 +102,34 @@ func (m model) Init() tea.Cmd {
 	return tea.Batch(
 		waitForResults(m.resultsChan),
 		tea.EnterAltScreen,
+		tick(),
 	)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// The outcome of the last batch is shown until the next key.
+		m.showBatch = false
+		if m.editingFilter {
+			return m.updateFilterInput(msg)
+		}
+		if m.showLog {
+			return m.updateLog(msg)
+		}
 		if m.showConfirm {
 			switch msg.String() {
 			case "y", "Y":
-				return m.deleteFiles()
+				if m.keepsNoCopy() {
+					return m, nil
+				}
+				if m.confirmAction == "delete" {
+					return m.applyPending(m.actionOpts.removeAction())
+				}
+				return m.applyPending(m.confirmAction)
 			case "n", "N", "esc":
 				m.showConfirm = false
-				m.toDelete = nil
+				m.pending = nil
 			}
 			return m, nil
 		}
@@ -70,6 +138,43 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "ctrl+c", "q":
 			m.quitting = true
 			return m, tea.Quit
+		case "/":
+			m.editingFilter = true
+			m.filterInput = m.filter.text
+			return m, nil
+		case "u":
+			return m.undoBatch()
+		case "L":
+			m.showLog = true
+			m.logOffset = max(0, len(m.log)-m.listHeight())
+			return m, nil
+		}
+		if m.overview {
+			return m.updateOverview(msg)
+		}
+
+		switch msg.String() {
+		case "esc":
+			m.overview = true
+			m.overviewOffset = m.overviewScrollOffset()
+
+		case "p":
+			m.hidePreview = !m.hidePreview
+
+		case "n":
+			m = m.nextMatch(1)
+
+		case "N":
+			m = m.nextMatch(-1)
+
+		case "f":
+			// Show only the groups with a file in the highlighted file's
+			// directory.
+			f, err := parseFilter(`dir:"` + m.currentDir() + `"`)
+			if err != nil {
+				return m, nil
+			}
+			return m.applyFilter(f)
 
 		case "up", "k":
 			if len(m.groups) == 0 {
@@ -83,27 +188,44 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if len(m.groups) == 0 {
 				return m, nil
 			}
-			if m.currentFile < len(m.groups[m.currentGroup])-1 {
+			if m.currentFile < len(m.groups[m.currentGroup].files)-1 {
 				m.currentFile++
 			}
 
-		case "left", "h":
+		case "pgup":
 			if len(m.groups) == 0 {
 				return m, nil
 			}
-			if m.currentGroup > 0 {
-				m.currentGroup--
-				m.currentFile = 0
+			m.currentFile = max(0, m.currentFile-m.listHeight())
+
+		case "pgdown":
+			if len(m.groups) == 0 {
+				return m, nil
 			}
+			m.currentFile = min(len(m.groups[m.currentGroup].files)-1, m.currentFile+m.listHeight())
 
-		case "right", "l":
+		case "home", "g":
+			m.currentFile = 0
+
+		case "end", "G":
 			if len(m.groups) == 0 {
 				return m, nil
 			}
-			if m.currentGroup < len(m.groups)-1 {
-				m.currentGroup++
-				m.currentFile = 0
+			m.currentFile = len(m.groups[m.currentGroup].files) - 1
+
+		case "left", "h":
+			if len(m.groups) == 0 {
+				return m, nil
 			}
+			m.currentGroup = m.stepGroup(-1)
+			m.currentFile = 0
+
+		case "right", "l":
+			if len(m.groups) == 0 {
+				return m, nil
+			}
+			m.currentGroup = m.stepGroup(1)
+			m.currentFile = 0
 
 		case " ":
 			if len(m.groups) == 0 {
@@ -122,24 +244,95 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.selected[group][file] = struct{}{}
 			}
 
+		case "a":
+			m.markAllBut(m.currentGroup, m.currentFile, len(m.groups[m.currentGroup].files))
+
+		case "m":
+			m.markKept(m.currentGroup)
+
+		case "M":
+			for _, groupIdx := range m.groupOrder() {
+				m.markKept(groupIdx)
+			}
+
+		case "i":
+			m.invertSelection(m.currentGroup)
+
+		case "c":
+			delete(m.selected, m.currentGroup)
+
+		case "C":
+			m.selected = make(map[int]map[int]struct{})
+
+		case "D":
+			m.selectInDir(m.currentDir())
+
 		case "d":
 			if len(m.groups) == 0 {
 				return m, nil
 			}
-			m.toDelete = m.getSelectedFiles()
-			if len(m.toDelete) > 0 {
+			m.pending = m.selectedTargets()
+			if len(m.pending) > 0 {
+				m.confirmAction = "delete"
+				m.showConfirm = true
+			}
+
+		case "H":
+			if len(m.groups) == 0 {
+				return m, nil
+			}
+			m.pending = m.selectedTargets()
+			if len(m.pending) > 0 {
+				m.confirmAction = "hardlink"
+				m.showConfirm = true
+			}
+
+		case "S":
+			if len(m.groups) == 0 {
+				return m, nil
+			}
+			m.pending = m.selectedTargets()
+			if len(m.pending) > 0 {
+				m.confirmAction = "symlink"
+				m.showConfirm = true
+			}
+
+		case "R":
+			if len(m.groups) == 0 {
+				return m, nil
+			}
+			m.pending = m.selectedTargets()
+			if len(m.pending) > 0 {
+				m.confirmAction = "reflink"
 				m.showConfirm = true
 			}
 
 		}
+		m.offset = m.scrollOffset()
+		return m, m.previewCmd()
+
+	case filePreview:
+		m.preview = msg
+		return m, nil
 
 	case scanCompleteMsg:
 		m.scanning = false
+		m.finished = time.Now()
 		return m, nil
 
-	case []string:
-		m.scanning = false
+	case tickMsg:
+		if !m.scanning {
+			return m, nil
+		}
+		m.spinner++
+		return m, tick()
+
+	case dupGroup:
+		if len(m.actionOpts.keep) > 0 {
+			m.markAllBut(len(m.groups), m.actionOpts.keep.choose(msg), len(msg.files))
+		}
 		m.groups = append(m.groups, msg)
+		m.overviewOffset = m.overviewScrollOffset()
 		return m, waitForResults(m.resultsChan)
 
 	case error:
@@ -147,25 +340,117 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Quit
 
 	case tea.WindowSizeMsg:
-		return m, nil
+		m.width, m.height = msg.Width, msg.Height
+		m.offset = m.scrollOffset()
+		m.overviewOffset = m.overviewScrollOffset()
+		return m, m.previewCmd()
 	}
 
 	return m, nil
 }
 
-func (m model) getSelectedFiles() []string {
-	var toDelete []string
-	for groupIdx, files := range m.selected {
+// markAllBut selects every file of the group at groupIdx, which holds n
+// files, except the one at keepIdx.
+func (m model) markAllBut(groupIdx, keepIdx, n int) {
+	m.selected[groupIdx] = make(map[int]struct{})
+	for i := range n {
+		if i != keepIdx {
+			m.selected[groupIdx][i] = struct{}{}
+		}
+	}
+}
+
+// listHeight returns how many files of a group fit on the screen below the
+// title and above the help text.
+func (m model) listHeight() int {
+	// Before the first WindowSizeMsg everything fits, but the height stays
+	// finite so that adding it to a position cannot overflow.
+	if m.height == 0 {
+		rows := max(1, len(m.groups), len(m.log))
+		for _, g := range m.groups {
+			rows = max(rows, len(g.files))
+		}
+		return rows
+	}
+	// The title, a blank line, the group header, a blank line and the
+	// status bar.
+	const chrome = 5
+	height := m.height - chrome - lipgloss.Height(m.helpView())
+	if panel := m.batchView(); panel != "" {
+		height -= lipgloss.Height(panel) - 1
+	}
+	return max(1, height)
+}
+
+// scrollOffset returns the offset of the file list that keeps the cursor
+// visible.
+func (m model) scrollOffset() int {
+	if len(m.groups) == 0 {
+		return 0
+	}
+	return scrollTo(m.offset, m.currentFile, len(m.groups[m.currentGroup].files), m.listHeight())
+}
+
+// scrollTo returns the offset of a list of n rows, height of which are
+// visible, that keeps the row at cursor visible, moving as little as
+// possible from offset.
+func scrollTo(offset, cursor, n, height int) int {
+	if cursor < offset {
+		offset = cursor
+	}
+	if cursor >= offset+height {
+		offset = cursor - height + 1
+	}
+	return max(0, min(offset, n-height))
+}
+
+// truncateMiddle shortens s to width cells by replacing its middle with an
+// ellipsis, which keeps both the start of a path and its file name visible.
+func truncateMiddle(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	if width < 2 {
+		return "…"
+	}
+	runes := []rune(s)
+	tail := (width - 1) / 2
+	head := width - 1 - tail
+	return string(runes[:head]) + "…" + string(runes[len(runes)-tail:])
+}
+
+// selectedTargets pairs every selected file with the first unselected file of
+// its group, which is the copy kept by link actions. Targets are in the
+// order of the groups and of their files.
+func (m model) selectedTargets() []actionTarget {
+	var targets []actionTarget
+	for _, groupIdx := range slices.Sorted(maps.Keys(m.selected)) {
+		files := m.selected[groupIdx]
 		if groupIdx >= len(m.groups) {
 			continue
 		}
-		for fileIdx := range files {
-			if fileIdx < len(m.groups[groupIdx]) {
-				toDelete = append(toDelete, m.groups[groupIdx][fileIdx])
+		group := m.groups[groupIdx]
+
+		var keep dupFile
+		for fileIdx, f := range group.files {
+			if _, selected := files[fileIdx]; !selected {
+				keep = f
+				break
+			}
+		}
+
+		for _, fileIdx := range slices.Sorted(maps.Keys(files)) {
+			if fileIdx < len(group.files) {
+				targets = append(targets, actionTarget{
+					file: group.files[fileIdx],
+					keep: keep,
+					size: group.size,
+					hash: group.hash,
+				})
 			}
 		}
 	}
-	return toDelete
+	return targets
 }
 
 func (m model) View() string {
@@ -181,104 +466,210 @@ func (m model) View() string {
 	b.WriteString(titleStyle.Render("Duplicate File Finder") + "\n\n")
 
 	if m.showConfirm {
-		return confirmStyle.Render(
-			fmt.Sprintf("Delete %d selected files?\n\n", len(m.toDelete))+
-				"[y] Yes  [n] No\n") +
-			helpStyle.Render("(This cannot be undone)")
+		question := fmt.Sprintf("Delete %d selected files?", len(m.pending))
+		note := "(This cannot be undone)"
+		switch m.confirmAction {
+		case "delete":
+			switch {
+			case m.actionOpts.quarantineDir != "":
+				question = fmt.Sprintf("Move %d selected files to %s?", len(m.pending), m.actionOpts.quarantineDir)
+				note = "(Press u afterwards to undo, or restore them with: dugo restore " + m.actionOpts.quarantineDir + ")"
+			case m.actionOpts.trash:
+				question = fmt.Sprintf("Move %d selected files to the trash?", len(m.pending))
+				note = "(Press u afterwards to undo, or restore them from the trash)"
+			}
+		case "hardlink", "symlink":
+			question = fmt.Sprintf("Replace %d selected files with %ss to an unselected copy?", len(m.pending), m.confirmAction)
+			note = "(Press u afterwards to undo)"
+		case "reflink":
+			question = fmt.Sprintf("Share the data of %d selected files with an unselected copy?", len(m.pending))
+			note = "(Every file keeps its own path and content)"
+		}
+		answers := "[y] Yes  [n] No"
+		if m.keepsNoCopy() {
+			answers = "Unselect a copy in each of those groups first.  [n] Back"
+		}
+		return confirmStyle.Render(question+"\n"+m.selectionTotals()+"\n\n"+answers+"\n") +
+			helpStyle.Render(note)
 	}
 
-	if m.scanning {
+	if m.showLog {
+		b.WriteString(m.viewLog() + "\n")
+	} else if len(m.groups) == 0 && m.scanning {
 		b.WriteString("🔍 Scanning for duplicates...\n")
 	} else if len(m.groups) == 0 {
 		b.WriteString("🎉 No duplicates found!\n")
+	} else if m.overview {
+		m.viewOverview(&b)
 	} else {
-		current := m.groups[m.currentGroup]
-		b.WriteString(fmt.Sprintf(" Group %d/%d (%d files)\n",
-			m.currentGroup+1, len(m.groups), len(current)))
-
-		for i, file := range current {
-			var line strings.Builder
-			if _, selected := m.selected[m.currentGroup][i]; selected {
-				line.WriteString(selectedFileStyle.Render("◉ "))
-			} else {
-				line.WriteString("◌ ")
-			}
+		b.WriteString(m.viewGroup() + "\n")
+	}
 
-			if i == m.currentFile {
-				line.WriteString("➔ ")
-			} else {
-				line.WriteString("  ")
-			}
+	b.WriteString("\n" + m.batchView() + m.statusView() + "\n" + m.helpView())
 
-			line.WriteString(fileStyle.Render(file))
+	return b.String()
+}
 
-			if _, selected := m.selected[m.currentGroup][i]; selected {
-				line.WriteString(deleteStyle.Render(" (marked for deletion)"))
-			}
+// previewWidth returns the width of the preview pane, or 0 if it is hidden
+// or the terminal is too narrow for it.
+func (m model) previewWidth() int {
+	if m.hidePreview || m.width < 80 {
+		return 0
+	}
+	return m.width * 2 / 5
+}
 
-			b.WriteString(line.String() + "\n")
-		}
+// viewGroup renders the files of the current group, next to the preview
+// pane of the highlighted one.
+func (m model) viewGroup() string {
+	var b strings.Builder
+	current := m.groups[m.currentGroup]
+	start := min(m.offset, len(current.files))
+	end := start + min(len(current.files)-start, m.listHeight())
+	order := m.groupOrder()
+	b.WriteString(fmt.Sprintf(" Group %d/%d (%d files)",
+		slices.Index(order, m.currentGroup)+1, len(order), len(current.files)))
+	if start > 0 || end < len(current.files) {
+		b.WriteString(fmt.Sprintf(" • showing %d-%d", start+1, end))
 	}
 
-	helpText := ""
-	if !m.showConfirm {
-		if m.scanning {
-			helpText = helpStyle.Render("(Press q to quit)")
-		} else if len(m.groups) > 0 {
-			helpText = helpStyle.Render(
-				"↑/↓: Navigate files • ←/→: Switch groups • Space: Select • d: Delete selected • q: Quit",
-			)
+	paneWidth := m.previewWidth()
+	listWidth := m.width - paneWidth
+	const marker = " (marked for deletion)"
+	for i := start; i < end; i++ {
+		file := current.files[i]
+		var line strings.Builder
+		if _, selected := m.selected[m.currentGroup][i]; selected {
+			line.WriteString(selectedFileStyle.Render("◉ "))
 		} else {
-			helpText = helpStyle.Render("(Press q to quit)")
+			line.WriteString("◌ ")
 		}
-	}
 
-	if helpText != "" {
-		b.WriteString("\n\n" + helpText)
-	}
+		if i == m.currentFile {
+			line.WriteString("➔ ")
+		} else {
+			line.WriteString("  ")
+		}
 
-	return b.String()
-}
+		path := file.path
+		if m.width > 0 {
+			// Leave room for the markers before the path and after it.
+			path = truncateMiddle(path, max(8, listWidth-4-len(marker)))
+		}
+		if m.filter.active() && m.filter.matchFile(file.path) {
+			line.WriteString(matchStyle.Render(path))
+		} else {
+			line.WriteString(fileStyle.Render(path))
+		}
 
-func (m model) deleteFiles() (tea.Model, tea.Cmd) {
-	deleted := 0
-	for _, path := range m.toDelete {
-		if err := os.Remove(path); err == nil {
-			deleted++
-			m.removeDeletedFile(path)
+		if _, selected := m.selected[m.currentGroup][i]; selected {
+			line.WriteString(deleteStyle.Render(marker))
 		}
-	}
 
-	m.selected = make(map[int]map[int]struct{})
-	m.toDelete = nil
-	m.showConfirm = false
+		b.WriteString("\n" + line.String())
+	}
 
-	return m, tea.Batch(
-		tea.Printf("%s %d files deleted", deleteStyle.Render("✔"), deleted),
-	)
+	if paneWidth == 0 {
+		return b.String()
+	}
+	list := lipgloss.NewStyle().Width(listWidth).Render(b.String())
+	// The pane can use the rows of the header and of a full list.
+	return lipgloss.JoinHorizontal(lipgloss.Top, list, m.viewPreview(paneWidth, m.listHeight()+1))
 }
 
-func (m model) removeDeletedFile(path string) {
-	for groupIdx, group := range m.groups {
-		for fileIdx, filePath := range group {
-			if filePath == path {
-				m.groups[groupIdx] = append(group[:fileIdx], group[fileIdx+1:]...)
+// viewPreview renders the preview pane of the highlighted file within width
+// columns and at most height lines.
+func (m model) viewPreview(width, height int) string {
+	path := m.groups[m.currentGroup].files[m.currentFile].path
+	// The pane has a border and a padding column on its left.
+	inner := width - 2
 
-				if groupIdx == m.currentGroup && fileIdx <= m.currentFile {
-					m.currentFile = max(0, m.currentFile-1)
-				}
-				break
+	var lines []string
+	for line := range strings.Lines(lipgloss.NewStyle().Width(inner).Render(path)) {
+		lines = append(lines, strings.TrimRight(line, "\n"))
+	}
+	lines = append(lines, "")
+	switch {
+	case m.preview.path != path:
+		lines = append(lines, helpStyle.Render("Loading…"))
+	case m.preview.err != nil:
+		lines = append(lines, m.preview.details...)
+		lines = append(lines, errorStyle.Render(truncateEnd(m.preview.err.Error(), inner)))
+	default:
+		lines = append(lines, m.preview.details...)
+		if len(m.preview.content) > 0 {
+			lines = append(lines, "")
+			for _, line := range m.preview.content {
+				lines = append(lines, helpStyle.Render(truncateEnd(line, inner)))
 			}
 		}
+	}
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	// Width includes the padding but not the border.
+	return previewStyle.Width(width - 1).Render(strings.Join(lines, "\n"))
+}
 
-		if len(m.groups[groupIdx]) == 0 {
-			m.groups = append(m.groups[:groupIdx], m.groups[groupIdx+1:]...)
-			m.currentGroup = max(0, min(m.currentGroup, len(m.groups)-1))
+// truncateEnd shortens s to width cells, ending it with an ellipsis.
+func truncateEnd(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && lipgloss.Width(string(runes)) > width-1 {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+// previewCmd loads the preview of the highlighted file in the background,
+// unless it is already loaded or not shown.
+func (m model) previewCmd() tea.Cmd {
+	if m.overview || len(m.groups) == 0 || m.previewWidth() == 0 {
+		return nil
+	}
+	files := m.groups[m.currentGroup].files
+	if m.currentFile >= len(files) || files[m.currentFile].path == m.preview.path {
+		return nil
+	}
+	path := files[m.currentFile].path
+	return func() tea.Msg { return loadPreview(path) }
+}
+
+func (m model) helpView() string {
+	style := helpStyle
+	if m.width > 0 {
+		style = style.Width(m.width)
+	}
+	if m.editingFilter {
+		input := "/" + m.filterInput + "█"
+		if m.filterErr != "" {
+			input += "  " + errorStyle.Render(m.filterErr)
 		}
+		return input + "\n" + style.Render("Enter: Apply • Esc: Cancel • Terms: text, re:<regex>, dir:<dir>, size:<min>-<max>")
+	}
+	if m.showLog {
+		return style.Render("↑/↓: Scroll • PgUp/PgDn/Home/End: Scroll by page • L/Esc: Close the log • q: Quit")
 	}
+	undo := ""
+	if m.canUndo() {
+		undo = "u: Undo last action • "
+	}
+	if len(m.groups) == 0 {
+		return style.Render(undo + "L: Log • (Press q to quit)")
+	}
+	if m.overview {
+		return style.Render(
+			"↑/↓: Navigate groups • PgUp/PgDn/Home/End: Scroll • Enter: Open group • r/s/c: Sort by reclaimable/size/count • M: Select all but the kept files • C: Clear selection • /: Filter • Esc: Clear filter • " + undo + "L: Log • q: Quit",
+		)
+	}
+	return style.Render(
+		"↑/↓: Navigate files • PgUp/PgDn/Home/End: Scroll • ←/→: Switch groups • Esc: Overview • p: Toggle preview • /: Filter • n/N: Next/previous match • f: Filter by directory • Space: Select • a: Select all but this • m/M: Select all but the kept file (group/all) • i: Invert • c/C: Clear (group/all) • D: Select all in this directory • d: Delete selected • H/S/R: Hardlink/symlink/reflink selected • " + undo + "L: Log • q: Quit",
+	)
 }
 
-func waitForResults(results <-chan []string) tea.Cmd {
+func waitForResults(results <-chan dupGroup) tea.Cmd {
 	return func() tea.Msg {
 		group, ok := <-results
 		if !ok {
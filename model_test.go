@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// testModel returns a model holding groups with the given numbers of files,
+// as if the scan had completed.
+func testModel(sizes ...int) model {
+	m := initialModel(nil, nil, actionOptions{})
+	m.scanning = false
+	m.finished = m.started
+	for i, n := range sizes {
+		g := dupGroup{size: 1, hash: fmt.Sprint(i)}
+		for j := range n {
+			g.files = append(g.files, dupFile{path: fmt.Sprintf("/data/group%d/file%03d.txt", i, j)})
+		}
+		m.groups = append(m.groups, g)
+	}
+	return m
+}
+
+// update feeds msgs to m in order and returns the resulting model.
+func update(m model, msgs ...tea.Msg) model {
+	for _, msg := range msgs {
+		next, _ := m.Update(msg)
+		m = next.(model)
+	}
+	return m
+}
+
+func TestModelScrolling(t *testing.T) {
+	m := update(testModel(300), tea.WindowSizeMsg{Width: 200, Height: 20}, tea.KeyMsg{Type: tea.KeyEnter})
+	height := m.listHeight()
+	if height >= 300 || height < 1 {
+		t.Fatalf("expected the list to be shorter than the group, got: %d rows", height)
+	}
+
+	tests := []struct {
+		name       string
+		key        tea.KeyType
+		wantFile   int
+		wantOffset int
+	}{
+		{name: "Page down", key: tea.KeyPgDown, wantFile: height, wantOffset: 1},
+		{name: "End", key: tea.KeyEnd, wantFile: 299, wantOffset: 300 - height},
+		{name: "Page up", key: tea.KeyPgUp, wantFile: 299 - height, wantOffset: 299 - height},
+		{name: "Up", key: tea.KeyUp, wantFile: 298 - height, wantOffset: 298 - height},
+		{name: "Home", key: tea.KeyHome, wantFile: 0, wantOffset: 0},
+	}
+	for _, tt := range tests {
+		m = update(m, tea.KeyMsg{Type: tt.key})
+		if m.currentFile != tt.wantFile || m.offset != tt.wantOffset {
+			t.Errorf("%s: expected file %d at offset %d, got: file %d at offset %d",
+				tt.name, tt.wantFile, tt.wantOffset, m.currentFile, m.offset)
+		}
+	}
+
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnd})
+	view := m.View()
+	if !strings.Contains(view, "file299.txt") || strings.Contains(view, "file000.txt") {
+		t.Errorf("expected the view to show the end of the group only, got:\n%s", view)
+	}
+	if lines := lipgloss.Height(view); lines > 20 {
+		t.Errorf("expected the view to fit in 20 lines, got: %d", lines)
+	}
+}
+
+func TestModelScrollingBeforeResize(t *testing.T) {
+	// No WindowSizeMsg yet, so the whole group fits.
+	m := update(testModel(3, 2), tea.KeyMsg{Type: tea.KeyEnter}, tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyPgDown})
+	if m.currentFile != 2 {
+		t.Errorf("expected PgDn to move to the last file, got: %d", m.currentFile)
+	}
+	m = update(m, tea.KeyMsg{Type: tea.KeyEsc}, tea.KeyMsg{Type: tea.KeyPgDown})
+	if m.currentGroup != 1 {
+		t.Errorf("expected PgDn to move to the last group, got: %d", m.currentGroup)
+	}
+	m = update(m, tea.WindowSizeMsg{Width: 80, Height: 20}, tea.KeyMsg{Type: tea.KeyEnter})
+	if dir := m.currentDir(); dir != "/data/group1" {
+		t.Errorf("expected the directory of the current file, got: %s", dir)
+	}
+}
+
+func TestModelOverview(t *testing.T) {
+	m := testModel(2, 5, 3)
+	m.groups[0].size = 1000 // 1000 bytes reclaimable
+	m.groups[1].size = 10   // 40 bytes reclaimable
+	m.groups[2].size = 100  // 200 bytes reclaimable
+
+	tests := []struct {
+		sortKey  rune
+		expected []int
+	}{
+		{sortKey: 'r', expected: []int{0, 2, 1}},
+		{sortKey: 's', expected: []int{0, 2, 1}},
+		{sortKey: 'c', expected: []int{1, 2, 0}},
+	}
+	for _, tt := range tests {
+		m = update(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{tt.sortKey}})
+		if order := m.groupOrder(); !equal(order, tt.expected) {
+			t.Errorf("sorting by %q: expected %v, got: %v", tt.sortKey, tt.expected, order)
+		}
+	}
+
+	// Sorted by count, the second row is group 2, and → in the group view
+	// moves on to the next row, group 0.
+	m = update(m, tea.KeyMsg{Type: tea.KeyHome}, tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.overview || m.currentGroup != 2 {
+		t.Fatalf("expected to open group 2, got: overview %v, group %d", m.overview, m.currentGroup)
+	}
+	m = update(m, tea.KeyMsg{Type: tea.KeyRight})
+	if m.currentGroup != 0 {
+		t.Errorf("expected → to open group 0, got: %d", m.currentGroup)
+	}
+	m = update(m, tea.KeyMsg{Type: tea.KeyEsc})
+	if !m.overview {
+		t.Error("expected Esc to go back to the overview")
+	}
+	if view := m.View(); !strings.Contains(view, "3 groups • 1.2 KiB reclaimable") {
+		t.Errorf("expected the overview to show the totals, got:\n%s", view)
+	}
+}
+
+func TestModelPreview(t *testing.T) {
+	tempDir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a.txt", "b.txt"} {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte("hello from "+name), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	g, err := newDupGroup(int64(len("hello from a.txt")), "", paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := initialModel(nil, nil, actionOptions{})
+	m.scanning = false
+	m.groups = []dupGroup{g}
+
+	m = update(m, tea.WindowSizeMsg{Width: 120, Height: 30})
+	next, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = next.(model)
+	if cmd == nil {
+		t.Fatal("expected opening a group to load a preview")
+	}
+	if view := m.View(); !strings.Contains(view, "Loading…") {
+		t.Errorf("expected the preview to be loading, got:\n%s", view)
+	}
+
+	m = update(m, cmd())
+	if view := m.View(); !strings.Contains(view, "hello from a.txt") || !strings.Contains(view, "Links:") {
+		t.Errorf("expected the preview of a.txt, got:\n%s", view)
+	}
+
+	next, cmd = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = update(next.(model), cmd())
+	if view := m.View(); !strings.Contains(view, "hello from b.txt") {
+		t.Errorf("expected the preview to follow the cursor, got:\n%s", view)
+	}
+
+	m = update(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if view := m.View(); strings.Contains(view, "hello from b.txt") {
+		t.Errorf("expected p to hide the preview, got:\n%s", view)
+	}
+}
+
+func TestModelScanStatus(t *testing.T) {
+	var stats scanStats
+	stats.walked.Store(25)
+	m := initialModel(nil, &stats, actionOptions{})
+	if status := m.statusView(); !strings.Contains(status, "Scanning • 25 files found") {
+		t.Errorf("expected the walk to be shown in the status bar, got: %q", status)
+	}
+
+	stats.walkDone.Store(true)
+	stats.files.Store(10)
+	stats.bytes.Store(10 * 1024)
+	stats.filesDone.Store(4)
+	stats.bytesDone.Store(4 * 1024)
+
+	m = update(m, dupGroup{size: 1024, files: []dupFile{{path: "/a"}, {path: "/b"}}})
+	if !m.scanning {
+		t.Fatal("expected the scan to be in progress until the results are closed")
+	}
+	status := m.statusView()
+	for _, want := range []string{"Scanning", "4/10 files", "4.0 KiB/10.0 KiB compared", "1 groups"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("expected %q in the status bar, got: %q", want, status)
+		}
+	}
+
+	next, cmd := m.Update(tickMsg(time.Now()))
+	m = next.(model)
+	if m.spinner != 1 || cmd == nil {
+		t.Errorf("expected a tick to advance the spinner and schedule the next one, got: %d, %v", m.spinner, cmd)
+	}
+
+	stats.filesDone.Store(10)
+	stats.errors.Store(2)
+	m = update(m, scanCompleteMsg{})
+	if m.scanning {
+		t.Fatal("expected the scan to be complete")
+	}
+	if _, cmd := m.Update(tickMsg(time.Now())); cmd != nil {
+		t.Error("expected ticks to stop once the scan is complete")
+	}
+	status = m.statusView()
+	for _, want := range []string{"Scan complete", "10 files", "1 groups", "2 errors"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("expected %q in the status bar, got: %q", want, status)
+		}
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	tests := []struct {
+		input    string
+		width    int
+		expected string
+	}{
+		{input: "/short", width: 10, expected: "/short"},
+		{input: "/home/user/photos/2023/img.jpg", width: 15, expected: "/home/u…img.jpg"},
+		{input: "/home/user/photos/2023/img.jpg", width: 1, expected: "…"},
+		{input: "/données/très/longues.txt", width: 9, expected: "/don….txt"},
+	}
+	for _, tt := range tests {
+		if got := truncateMiddle(tt.input, tt.width); got != tt.expected {
+			t.Errorf("truncateMiddle(%q, %d): expected %q, got: %q", tt.input, tt.width, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCopyFile(t *testing.T) {
+	tempDir := t.TempDir()
+	src := filepath.Join(tempDir, "src.txt")
+	dst := filepath.Join(tempDir, "dst.txt")
+
+	if err := os.WriteFile(src, []byte("content"), 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := copyFile(src, dst); err != nil {
+		t.Fatalf("copyFile failed: %v", err)
+	}
+
+	b, err := os.ReadFile(dst)
+	if err != nil || string(b) != "content" {
+		t.Errorf("expected %q, got: %q, %v", "content", b, err)
+	}
+	fi, err := os.Stat(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(mtime) {
+		t.Errorf("expected mtime %v, got: %v", mtime, fi.ModTime())
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("expected mode %v, got: %v", os.FileMode(0600), fi.Mode().Perm())
+	}
+
+	if err := copyFile(src, dst); err == nil {
+		t.Error("expected an error copying over an existing file, got nil")
+	}
+}
+
+func TestMoveFile(t *testing.T) {
+	tempDir := t.TempDir()
+	src := filepath.Join(tempDir, "src.txt")
+	dst := filepath.Join(tempDir, "a", "b", "dst.txt")
+
+	if err := os.WriteFile(src, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if err := moveFile(src, dst); err != nil {
+		t.Fatalf("moveFile failed: %v", err)
+	}
+	assertFileExists(t, src, false)
+	assertFileExists(t, dst, true)
+}
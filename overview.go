@@ -0,0 +1,151 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// reclaimable returns the space freed by keeping a single file of g.
+func (g dupGroup) reclaimable() int64 {
+	return g.size * int64(len(g.files)-1)
+}
+
+// groupOrder returns the indices of the groups matching the filter in the
+// order of the overview, sorted by m.sortBy, largest first, and in arrival
+// order on ties.
+func (m model) groupOrder() []int {
+	key := func(g dupGroup) int64 {
+		switch m.sortBy {
+		case "size":
+			return g.size
+		case "count":
+			return int64(len(g.files))
+		default:
+			return g.reclaimable()
+		}
+	}
+	order := make([]int, 0, len(m.groups))
+	for i, g := range m.groups {
+		if m.filter.matchGroup(g) {
+			order = append(order, i)
+		}
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(key(m.groups[b]), key(m.groups[a]))
+	})
+	return order
+}
+
+// stepGroup returns the index of the group delta rows away from the current
+// one in the overview order, stopping at the first and last groups.
+func (m model) stepGroup(delta int) int {
+	order := m.groupOrder()
+	if len(order) == 0 {
+		return m.currentGroup
+	}
+	pos := slices.Index(order, m.currentGroup) + delta
+	return order[max(0, min(pos, len(order)-1))]
+}
+
+// overviewScrollOffset returns the offset of the overview that keeps the
+// current group visible.
+func (m model) overviewScrollOffset() int {
+	pos := slices.Index(m.groupOrder(), m.currentGroup)
+	return scrollTo(m.overviewOffset, max(0, pos), len(m.groupOrder()), m.listHeight())
+}
+
+func (m model) updateOverview(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if len(m.groups) == 0 {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "up", "k", "N":
+		m.currentGroup = m.stepGroup(-1)
+	case "down", "j", "n":
+		m.currentGroup = m.stepGroup(1)
+	case "pgup":
+		m.currentGroup = m.stepGroup(-m.listHeight())
+	case "pgdown":
+		m.currentGroup = m.stepGroup(m.listHeight())
+	case "home", "g":
+		m.currentGroup = m.stepGroup(-len(m.groups))
+	case "end", "G":
+		m.currentGroup = m.stepGroup(len(m.groups))
+	case "r":
+		m.sortBy = "reclaimable"
+	case "s":
+		m.sortBy = "size"
+	case "c":
+		m.sortBy = "count"
+	case "M":
+		for _, groupIdx := range m.groupOrder() {
+			m.markKept(groupIdx)
+		}
+	case "C":
+		m.selected = make(map[int]map[int]struct{})
+	case "esc":
+		if m.filter.active() {
+			return m.applyFilter(groupFilter{})
+		}
+	case "enter":
+		if !m.filter.matchGroup(m.groups[m.currentGroup]) {
+			return m, nil
+		}
+		m.overview = false
+		m.currentFile = 0
+		m.offset = 0
+		return m, m.previewCmd()
+	}
+	m.overviewOffset = m.overviewScrollOffset()
+	return m, nil
+}
+
+func (m model) viewOverview(b *strings.Builder) {
+	order := m.groupOrder()
+	var total int64
+	for _, groupIdx := range order {
+		total += m.groups[groupIdx].reclaimable()
+	}
+
+	start := min(scrollTo(m.overviewOffset, max(0, slices.Index(order, m.currentGroup)), len(order), m.listHeight()), len(order))
+	end := start + min(len(order)-start, m.listHeight())
+	if m.filter.active() {
+		b.WriteString(fmt.Sprintf(" %d of %d groups match %q", len(order), len(m.groups), m.filter.text))
+	} else {
+		b.WriteString(fmt.Sprintf(" %d groups", len(m.groups)))
+	}
+	b.WriteString(fmt.Sprintf(" • %s reclaimable • sorted by %s", formatBytes(total), m.sortBy))
+	if start > 0 || end < len(order) {
+		b.WriteString(fmt.Sprintf(" • showing %d-%d", start+1, end))
+	}
+	b.WriteString("\n")
+
+	for _, groupIdx := range order[start:end] {
+		g := m.groups[groupIdx]
+		var line strings.Builder
+		if groupIdx == m.currentGroup {
+			line.WriteString("➔ ")
+		} else {
+			line.WriteString("  ")
+		}
+		columns := fmt.Sprintf("%4d files  %10s each  %10s reclaimable  ",
+			len(g.files), formatBytes(g.size), formatBytes(g.reclaimable()))
+		line.WriteString(columns)
+
+		path := g.files[0].path
+		if m.width > 0 {
+			path = truncateMiddle(path, max(8, m.width-2-len(columns)))
+		}
+		line.WriteString(fileStyle.Render(path))
+
+		if n := len(m.selected[groupIdx]); n > 0 {
+			line.WriteString(deleteStyle.Render(fmt.Sprintf(" (%d marked)", n)))
+		}
+		b.WriteString(line.String() + "\n")
+	}
+}
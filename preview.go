@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// previewBytes is how much of a file the preview pane reads.
+const previewBytes = 4096
+
+// filePreview is what the preview pane shows for a file: its metadata and
+// the start of its content. It is a tea.Msg, loaded in the background as
+// the cursor moves.
+type filePreview struct {
+	path    string
+	details []string
+	content []string
+	err     error
+}
+
+// loadPreview reads the metadata of path and a preview of its content: the
+// dimensions of images, the first lines of text files and a hex dump of the
+// start of other files.
+func loadPreview(path string) filePreview {
+	p := filePreview{path: path}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	sys := statSys(fi)
+	p.details = []string{
+		fmt.Sprintf("Size:     %s (%d bytes)", formatBytes(fi.Size()), fi.Size()),
+		"Modified: " + fi.ModTime().Format("2006-01-02 15:04:05"),
+		"Mode:     " + fi.Mode().String(),
+	}
+	if sys.uid >= 0 {
+		p.details = append(p.details, "Owner:    "+ownerName(sys.uid, sys.gid))
+	}
+	if sys.nlink > 0 {
+		p.details = append(p.details, fmt.Sprintf("Links:    %d", sys.nlink))
+	}
+	if !fi.Mode().IsRegular() {
+		return p
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		p.err = err
+		return p
+	}
+	defer f.Close()
+
+	if cfg, format, err := image.DecodeConfig(f); err == nil {
+		p.content = []string{fmt.Sprintf("%s image, %d×%d pixels", strings.ToUpper(format), cfg.Width, cfg.Height)}
+		return p
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		p.err = err
+		return p
+	}
+	buf := make([]byte, previewBytes)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		p.err = err
+		return p
+	}
+	buf = buf[:n]
+
+	if isText(buf, n == previewBytes) {
+		p.content = textLines(buf, n == previewBytes)
+	} else {
+		p.content = hexLines(buf)
+	}
+	return p
+}
+
+// ownerName returns "user:group" for uid and gid, using their numbers for
+// those that cannot be looked up.
+func ownerName(uid, gid int) string {
+	owner, group := strconv.Itoa(uid), strconv.Itoa(gid)
+	if u, err := user.LookupId(owner); err == nil {
+		owner = u.Username
+	}
+	if g, err := user.LookupGroupId(group); err == nil {
+		group = g.Name
+	}
+	return owner + ":" + group
+}
+
+// isText reports whether buf looks like UTF-8 text. If truncated is set,
+// buf was cut at an arbitrary point and may end in a partial character.
+func isText(buf []byte, truncated bool) bool {
+	if bytes.IndexByte(buf, 0) >= 0 {
+		return false
+	}
+	if truncated && len(buf) > utf8.UTFMax {
+		buf = buf[:len(buf)-utf8.UTFMax]
+	}
+	return utf8.Valid(buf)
+}
+
+// textLines splits buf into lines, dropping a last line that was cut short,
+// expanding tabs and replacing control characters that would garble the
+// terminal.
+func textLines(buf []byte, truncated bool) []string {
+	lines := strings.Split(string(buf), "\n")
+	if truncated && len(lines) > 1 {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		line = strings.ReplaceAll(strings.TrimSuffix(line, "\r"), "\t", "    ")
+		lines[i] = strings.Map(func(r rune) rune {
+			if r < ' ' || r == 0x7f || r == utf8.RuneError {
+				return '·'
+			}
+			return r
+		}, line)
+	}
+	return lines
+}
+
+// hexLines formats buf as a hex dump with 8 bytes per line, which fits a
+// narrow pane better than hex.Dump.
+func hexLines(buf []byte) []string {
+	var lines []string
+	for off := 0; off < len(buf); off += 8 {
+		chunk := buf[off:min(off+8, len(buf))]
+		var hex, ascii strings.Builder
+		for i, c := range chunk {
+			if i > 0 {
+				hex.WriteByte(' ')
+			}
+			fmt.Fprintf(&hex, "%02x", c)
+			if c < ' ' || c > '~' {
+				c = '.'
+			}
+			ascii.WriteByte(c)
+		}
+		lines = append(lines, fmt.Sprintf("%08x  %-23s  %s", off, hex.String(), ascii.String()))
+	}
+	return lines
+}
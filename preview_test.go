@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPreview(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewGray(image.Rect(0, 0, 32, 16))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		content  []byte
+		expected []string
+	}{
+		{
+			name:     "Text",
+			content:  []byte("first line\n\tindented\r\nbell\a\n"),
+			expected: []string{"first line", "    indented", "bell·", ""},
+		},
+		{
+			name:     "Binary",
+			content:  []byte{0x7f, 'E', 'L', 'F', 0, 1, 2, 3, 4},
+			expected: []string{"00000000  7f 45 4c 46 00 01 02 03  .ELF....", "00000008  04                       ."},
+		},
+		{
+			name:     "Image",
+			content:  img.Bytes(),
+			expected: []string{"PNG image, 32×16 pixels"},
+		},
+		{
+			name:     "Text cut at the preview size",
+			content:  []byte(strings.Repeat("line\n", previewBytes/5) + "cut"),
+			expected: strings.Split(strings.Repeat("line\n", previewBytes/5-1)+"line", "\n"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tempDir, strings.ReplaceAll(tt.name, " ", "_"))
+			if err := os.WriteFile(path, tt.content, 0640); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+
+			p := loadPreview(path)
+			if p.err != nil || p.path != path {
+				t.Fatalf("expected a preview of %s, got: %q, %v", path, p.path, p.err)
+			}
+			if strings.Join(p.content, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("expected content %q, got: %q", tt.expected, p.content)
+			}
+			details := strings.Join(p.details, "\n")
+			if !strings.Contains(details, "-rw-r-----") || !strings.Contains(details, "Modified: ") {
+				t.Errorf("expected the mode and mtime in the details, got: %q", p.details)
+			}
+		})
+	}
+}
+
+func TestLoadPreviewMissingFile(t *testing.T) {
+	p := loadPreview(filepath.Join(t.TempDir(), "missing"))
+	if p.err == nil {
+		t.Error("expected an error previewing a missing file, got nil")
+	}
+}
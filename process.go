@@ -4,11 +4,136 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"sync"
+	"sync/atomic"
 )
 
-func groupByHash(files []string, workers uint) (map[string][]string, error) {
+// dupGroup is a set of files confirmed to have identical content.
+type dupGroup struct {
+	size  int64
+	hash  string
+	files []dupFile
+}
+
+func (g dupGroup) paths() []string {
+	paths := make([]string, len(g.files))
+	for i, f := range g.files {
+		paths[i] = f.path
+	}
+	return paths
+}
+
+// scanStats counts what a findDuplicates run produced, and its progress
+// while it runs. It is safe for concurrent use.
+type scanStats struct {
+	groups atomic.Int64
+	errors atomic.Int64
+	// walked counts the files found while the directories are walked, until
+	// walkDone is set.
+	walked   atomic.Int64
+	walkDone atomic.Bool
+	// files and bytes are the totals of the files that have to be compared,
+	// being in a size class with others. filesDone and bytesDone count those
+	// whose size class has been processed.
+	files     atomic.Int64
+	bytes     atomic.Int64
+	filesDone atomic.Int64
+	bytesDone atomic.Int64
+}
+
+// exitCode reports the outcome of a completed scan as a process exit code.
+func (s *scanStats) exitCode() int {
+	switch {
+	case s.errors.Load() > 0:
+		return exitPartial
+	case s.groups.Load() > 0:
+		return exitDuplicates
+	default:
+		return exitNoDuplicates
+	}
+}
+
+// findDuplicates hashes and compares every size class in filesBySize that
+// holds at least two files, and sends each confirmed duplicate group on the
+// returned channel. The channel is closed once all size classes are done.
+// Files that cannot be read are logged, counted in stats and skipped.
+func findDuplicates(filesBySize map[int64]sameSizeFiles, workers uint, stats *scanStats) <-chan dupGroup {
+	sem := make(chan struct{}, workers)
+	results := make(chan dupGroup)
+
+	for size, v := range filesBySize {
+		if len(v) >= 2 {
+			stats.files.Add(int64(len(v)))
+			stats.bytes.Add(size * int64(len(v)))
+		}
+	}
+
+	go func() {
+		var wg sync.WaitGroup
+		for size, v := range filesBySize {
+			if len(v) < 2 {
+				continue
+			}
+			wg.Add(1)
+			go func(size int64, files sameSizeFiles) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				defer func() {
+					stats.filesDone.Add(int64(len(files)))
+					stats.bytesDone.Add(size * int64(len(files)))
+				}()
+
+				res := groupByHash(files, workers, stats)
+				for hash, v := range res {
+					groups, err := partitionIntoEqualGroups(v)
+					if err != nil {
+						log.Printf("Error: %v", err)
+						stats.errors.Add(1)
+						continue
+					}
+					for _, group := range groups {
+						if len(group) < 2 {
+							continue
+						}
+						g, err := newDupGroup(size, hash, group)
+						if err != nil {
+							log.Printf("Error: %v", err)
+							stats.errors.Add(1)
+							continue
+						}
+						stats.groups.Add(1)
+						results <- g
+					}
+				}
+			}(size, v)
+		}
+
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+func newDupGroup(size int64, hash string, paths []string) (dupGroup, error) {
+	g := dupGroup{size: size, hash: hash, files: make([]dupFile, 0, len(paths))}
+	for _, path := range paths {
+		f, err := statDupFile(path)
+		if err != nil {
+			return dupGroup{}, err
+		}
+		g.files = append(g.files, f)
+	}
+	return g, nil
+}
+
+// groupByHash groups files by the hash of their content, leaving out the
+// hashes of single files. Files that cannot be hashed are logged, counted in
+// stats and skipped.
+func groupByHash(files []string, workers uint, stats *scanStats) map[string][]string {
 	type hashResult struct {
 		hash string
 		file string
@@ -45,7 +170,9 @@ func groupByHash(files []string, workers uint) (map[string][]string, error) {
 	m := make(map[string][]string)
 	for res := range resultChan {
 		if res.err != nil {
-			return nil, res.err
+			log.Printf("Error: %v", res.err)
+			stats.errors.Add(1)
+			continue
 		}
 		m[res.hash] = append(m[res.hash], res.file)
 	}
@@ -57,7 +184,7 @@ func groupByHash(files []string, workers uint) (map[string][]string, error) {
 		}
 	}
 
-	return mm, nil
+	return mm
 }
 
 func filesAreEqual(file1, file2 string) (bool, error) {
@@ -58,9 +58,10 @@ func TestGroupByHash(t *testing.T) {
 		filesNames = append(filesNames, filepath.Join(tempDir, v.file))
 	}
 
-	m, err := groupByHash(filesNames, workers)
-	if err != nil {
-		t.Error(err)
+	var stats scanStats
+	m := groupByHash(append(filesNames, filepath.Join(tempDir, "missing.txt")), workers, &stats)
+	if stats.errors.Load() != 1 {
+		t.Errorf("expected the missing file to be skipped and counted, got: %d errors", stats.errors.Load())
 	}
 
 	if len(m) != 2 {
@@ -216,3 +217,86 @@ func TestPartitionIntoEqualGroups(t *testing.T) {
 		}
 	}
 }
+
+func TestFindDuplicates(t *testing.T) {
+	tempDir := t.TempDir()
+
+	contents := map[string]string{
+		"a1.txt": "hello a",
+		"a2.txt": "hello a",
+		"b1.txt": "hello b",
+		"c1.txt": "another size",
+	}
+	for name, content := range contents {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	var stats scanStats
+	filesBySize, err := scanDir(tempDir, nil, nil, &stats)
+	if err != nil {
+		t.Fatalf("scanDir failed: %v", err)
+	}
+
+	var groups []dupGroup
+	for g := range findDuplicates(filesBySize, 2, &stats) {
+		groups = append(groups, g)
+	}
+
+	if len(groups) != 1 {
+		t.Fatalf("expected 1 group, got: %d", len(groups))
+	}
+	g := groups[0]
+	if g.size != int64(len("hello a")) {
+		t.Errorf("expected group size %d, got: %d", len("hello a"), g.size)
+	}
+	paths := g.paths()
+	slices.Sort(paths)
+	expected := []string{filepath.Join(tempDir, "a1.txt"), filepath.Join(tempDir, "a2.txt")}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("expected paths %v, got: %v", expected, paths)
+	}
+	for _, f := range g.files {
+		if f.mtime.IsZero() {
+			t.Errorf("mtime of %s was not recorded", f.path)
+		}
+	}
+
+	if stats.groups.Load() != 1 || stats.errors.Load() != 0 {
+		t.Errorf("expected 1 group and 0 errors, got: %d and %d", stats.groups.Load(), stats.errors.Load())
+	}
+	if code := stats.exitCode(); code != exitDuplicates {
+		t.Errorf("expected exit code %d, got: %d", exitDuplicates, code)
+	}
+	// Only the three files of the same size have to be compared.
+	if stats.files.Load() != 3 || stats.filesDone.Load() != 3 || stats.bytes.Load() != 21 || stats.bytesDone.Load() != 21 {
+		t.Errorf("expected 3 files and 21 bytes processed, got: %d/%d files and %d/%d bytes",
+			stats.filesDone.Load(), stats.files.Load(), stats.bytesDone.Load(), stats.bytes.Load())
+	}
+}
+
+func TestScanStatsExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		groups   int64
+		errors   int64
+		expected int
+	}{
+		{"no duplicates", 0, 0, exitNoDuplicates},
+		{"duplicates", 3, 0, exitDuplicates},
+		{"errors without duplicates", 0, 1, exitPartial},
+		{"errors with duplicates", 2, 1, exitPartial},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stats scanStats
+			stats.groups.Store(tt.groups)
+			stats.errors.Store(tt.errors)
+			if code := stats.exitCode(); code != tt.expected {
+				t.Errorf("expected exit code %d, got: %d", tt.expected, code)
+			}
+		})
+	}
+}
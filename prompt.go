@@ -4,77 +4,218 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 )
 
-func handleDeletions(groups [][]string) {
+// handleDeletions asks on stdin which files of every group received on
+// results to remove, as a line-based alternative to the TUI for terminals
+// that cannot run it. Files are listed sorted by path. The answers
+// parseSelection accepts choose the files to remove; "s" or an empty line
+// skips the group and "q" stops. Ending an answer with "*" applies it to
+// every later group whose files are in the same directories too. It returns
+// the number of removals that failed.
+func handleDeletions(results <-chan dupGroup, opts actionOptions) int {
 	reader := bufio.NewReader(os.Stdin)
-	for _, group := range groups {
-		if len(group) < 2 {
+	remembered := make(map[string]string)
+	failed := 0
+	for g := range results {
+		if len(g.files) < 2 {
 			continue
 		}
+		g.files = slices.Clone(g.files)
+		slices.SortFunc(g.files, func(a, b dupFile) int { return strings.Compare(a.path, b.path) })
+		pattern := dirPattern(g)
 
-		fmt.Printf("\nDuplicate group (%d files):\n", len(group))
-		for i, path := range group {
-			fmt.Printf("[%d] %s\n", i+1, path)
+		fmt.Printf("\nDuplicate group (%d files, %s each):\n", len(g.files), formatBytes(g.size))
+		for i, f := range g.files {
+			fmt.Printf("[%d] %s  %s\n", i+1, f.mtime.Format("2006-01-02 15:04:05"), f.path)
 		}
 
-		for {
-			fmt.Print("Enter numbers to delete (space-separated, 'a' to abort): ")
-			input, _ := reader.ReadString('\n')
-			input = strings.TrimSpace(input)
+		if input, ok := remembered[pattern]; ok {
+			if input == "" || strings.EqualFold(input, "s") {
+				fmt.Println("Skipping as for the previous groups in these directories")
+				continue
+			}
+			fmt.Printf("Applying %q as for the previous groups in these directories\n", input)
+			if toDelete, err := parseSelection(input, g); err == nil {
+				failed += deleteFiles(g, toDelete, opts)
+				continue
+			}
+		}
 
-			if strings.ToLower(input) == "a" {
-				break
+		for {
+			fmt.Print("Delete which files ('?' for help): ")
+			input, err := reader.ReadString('\n')
+			if err != nil && input == "" {
+				fmt.Println()
+				return failed
 			}
+			input = strings.TrimSpace(input)
 
-			toDelete, err := parseDeleteInput(input, len(group))
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
+			input, applyToAll := strings.CutSuffix(input, "*")
+			input = strings.TrimSpace(input)
+			if applyToAll && (input == "?" || strings.EqualFold(input, "q")) {
+				fmt.Printf("Error: * cannot be appended to %q\n", input)
 				continue
 			}
-
-			if len(toDelete) == len(group) {
-				fmt.Println("Error: Cannot delete all files in group")
+			switch strings.ToLower(input) {
+			case "?":
+				fmt.Println(promptHelp)
 				continue
+			case "q":
+				return failed
+			case "", "s":
+			default:
+				toDelete, err := parseSelection(input, g)
+				if err != nil {
+					fmt.Printf("Error: %v\n", err)
+					continue
+				}
+				if len(toDelete) == len(g.files) {
+					fmt.Println("Error: Cannot delete all files in group")
+					continue
+				}
+				failed += deleteFiles(g, toDelete, opts)
+			}
+			if applyToAll {
+				remembered[pattern] = input
 			}
-
-			deleteFiles(group, toDelete)
 			break
 		}
 	}
+	return failed
+}
+
+const promptHelp = `  1 3 4   delete files 1, 3 and 4
+  2-5     delete files 2 to 5
+  ^1      delete every file but 1 (also: k 1)
+  a       delete every file but the first
+  o, n    keep only the oldest or the newest file
+  s       skip this group (also: an empty line)
+  q       quit
+  Append * to apply the answer to every later group in the same directories.`
+
+// dirPattern identifies the directories holding the files of g, in order.
+func dirPattern(g dupGroup) string {
+	dirs := make([]string, len(g.files))
+	for i, f := range g.files {
+		dirs[i] = filepath.Dir(f.path)
+	}
+	return strings.Join(dirs, "\x00")
 }
 
+// parseSelection returns the indices of the files of g chosen by input:
+// either an answer parseDeleteInput accepts, or "o" or "n" to choose every
+// file but the oldest or the newest one.
+func parseSelection(input string, g dupGroup) ([]int, error) {
+	var rule string
+	switch strings.ToLower(input) {
+	case "o":
+		rule = "oldest"
+	case "n":
+		rule = "newest"
+	default:
+		return parseDeleteInput(input, len(g.files))
+	}
+	keep, err := parseKeepRule(rule, nil)
+	if err != nil {
+		return nil, err
+	}
+	return allBut(keepRules{keep}.choose(g), len(g.files)), nil
+}
+
+// parseDeleteInput returns the indices of the files of a group of max files
+// chosen by input: 1-based numbers and ranges such as "2-5", "^N" or "k N"
+// to choose every file but N, or "a" to choose every file but the first.
 func parseDeleteInput(input string, max int) ([]int, error) {
-	if input == "" {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
 		return nil, nil
 	}
 
-	seen := make(map[int]bool)
-	var indices []int
-	for _, s := range strings.Split(input, " ") {
+	switch {
+	case len(fields) == 1 && strings.EqualFold(fields[0], "a"):
+		return allBut(0, max), nil
+	case len(fields) == 1 && strings.HasPrefix(fields[0], "^"),
+		len(fields) == 2 && strings.EqualFold(fields[0], "k"):
+		s := strings.TrimPrefix(fields[len(fields)-1], "^")
 		num, err := strconv.Atoi(s)
 		if err != nil || num < 1 || num > max {
 			return nil, fmt.Errorf("invalid number: %s", s)
 		}
-		if seen[num-1] {
-			continue
+		return allBut(num-1, max), nil
+	}
+
+	seen := make(map[int]bool)
+	var indices []int
+	for _, s := range fields {
+		first, last, isRange := strings.Cut(s, "-")
+		from, err := strconv.Atoi(first)
+		to := from
+		if err == nil && isRange {
+			to, err = strconv.Atoi(last)
+		}
+		if err != nil || from < 1 || to > max || from > to {
+			return nil, fmt.Errorf("invalid number: %s", s)
+		}
+		for num := from; num <= to; num++ {
+			if seen[num-1] {
+				continue
+			}
+			seen[num-1] = true
+			indices = append(indices, num-1)
 		}
-		seen[num-1] = true
-		indices = append(indices, num-1)
 	}
 	return indices, nil
 }
 
-func deleteFiles(group []string, indices []int) {
+// allBut returns the indices from 0 to n-1 except keepIdx.
+func allBut(keepIdx, n int) []int {
+	indices := make([]int, 0, n-1)
+	for i := range n {
+		if i != keepIdx {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// deleteFiles removes the files of g at indices, keeping the first file that
+// is not removed, and returns how many removals failed.
+func deleteFiles(g dupGroup, indices []int, opts actionOptions) int {
+	chosen := make(map[int]bool, len(indices))
 	for _, idx := range indices {
-		path := group[idx]
-		err := os.Remove(path)
-		if err != nil {
-			fmt.Printf("Failed to delete %s: %v\n", path, err)
+		chosen[idx] = true
+	}
+	keepIdx := -1
+	for i := range g.files {
+		if !chosen[i] {
+			keepIdx = i
+			break
+		}
+	}
+
+	if opts.journal != nil {
+		opts.journal.startBatch()
+	}
+
+	verb := opts.removeVerb()
+	failed := 0
+	for _, idx := range indices {
+		t := actionTarget{file: g.files[idx], size: g.size, hash: g.hash}
+		if keepIdx >= 0 {
+			t.keep = g.files[keepIdx]
+		}
+		rec := performAction(opts.removeAction(), t, opts, true)
+		if rec.Error != "" {
+			fmt.Printf("Failed to remove %s: %s\n", rec.Path, rec.Error)
+			failed++
 		} else {
-			fmt.Printf("Deleted: %s\n", path)
+			fmt.Printf("%s%s: %s\n", strings.ToUpper(verb[:1]), verb[1:], rec.Path)
 		}
 	}
+	return failed
 }
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestHandleDeletions(t *testing.T) {
@@ -37,13 +39,196 @@ func TestHandleDeletions(t *testing.T) {
 
 	os.Stdin = r
 
-	handleDeletions([][]string{filePaths})
+	handleDeletions(promptResults(t, filePaths), actionOptions{})
 
 	assertFileExists(t, filePaths[0], false)
 	assertFileExists(t, filePaths[1], true)
 	assertFileExists(t, filePaths[2], false)
 }
 
+func TestHandleDeletionsSkipKeepQuit(t *testing.T) {
+	oldStdin := os.Stdin
+	defer func() { os.Stdin = oldStdin }()
+
+	tmpDir := t.TempDir()
+	groups := make([][]string, 4)
+	for i := range groups {
+		for j := range 3 {
+			path := filepath.Join(tmpDir, fmt.Sprintf("group%d-file%d.txt", i, j))
+			if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			groups[i] = append(groups[i], path)
+		}
+	}
+
+	// Skip the first group, keep only the second file of the second group,
+	// retry an invalid answer on the third, then quit before the fourth.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe creation failed: %v", err)
+	}
+	defer r.Close()
+	if _, err := w.WriteString("s\nk 2\n1 2 3\n9\nq\n1\n"); err != nil {
+		t.Fatalf("Failed to write to pipe: %v", err)
+	}
+	w.Close()
+	os.Stdin = r
+
+	if failed := handleDeletions(promptResults(t, groups...), actionOptions{}); failed != 0 {
+		t.Errorf("expected no failed removals, got: %d", failed)
+	}
+
+	for _, path := range groups[0] {
+		assertFileExists(t, path, true)
+	}
+	assertFileExists(t, groups[1][0], false)
+	assertFileExists(t, groups[1][1], true)
+	assertFileExists(t, groups[1][2], false)
+	for _, path := range append(groups[2], groups[3]...) {
+		assertFileExists(t, path, true)
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	now := time.Now()
+	g := dupGroup{files: []dupFile{
+		{path: "/a", mtime: now},
+		{path: "/b", mtime: now.Add(-time.Hour)},
+		{path: "/c", mtime: now.Add(time.Hour)},
+	}}
+
+	tests := []struct {
+		input    string
+		expected []int
+	}{
+		{input: "o", expected: []int{0, 2}},
+		{input: "n", expected: []int{0, 1}},
+		{input: "1-2", expected: []int{0, 1}},
+	}
+	for _, tt := range tests {
+		result, err := parseSelection(tt.input, g)
+		if err != nil || !equal(result, tt.expected) {
+			t.Errorf("%q: expected %v, got: %v, %v", tt.input, tt.expected, result, err)
+		}
+	}
+}
+
+func TestHandleDeletionsApplyToAll(t *testing.T) {
+	oldStdin := os.Stdin
+	defer func() { os.Stdin = oldStdin }()
+
+	tmpDir := t.TempDir()
+	var groups [][]string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt", "d.txt"} {
+		var group []string
+		for _, dir := range []string{"main", "backup"} {
+			if name == "d.txt" {
+				dir = "other"
+			}
+			path := filepath.Join(tmpDir, dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			group = append(group, path)
+		}
+		groups = append(groups, group)
+	}
+	groups[3][1] = groups[0][0]
+
+	// Files are listed sorted, so backup comes first. The answer to the
+	// first group is reused for the next two, which are in the same
+	// directories, but not for the last one.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe creation failed: %v", err)
+	}
+	defer r.Close()
+	if _, err := w.WriteString("1 *\n"); err != nil {
+		t.Fatalf("Failed to write to pipe: %v", err)
+	}
+	w.Close()
+	os.Stdin = r
+
+	handleDeletions(promptResults(t, groups...), actionOptions{})
+
+	for _, group := range groups[:3] {
+		assertFileExists(t, group[0], true)
+		assertFileExists(t, group[1], false)
+	}
+	assertFileExists(t, groups[3][0], true)
+}
+
+func TestHandleDeletionsSkipAll(t *testing.T) {
+	oldStdin := os.Stdin
+	defer func() { os.Stdin = oldStdin }()
+
+	tmpDir := t.TempDir()
+	var groups [][]string
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		var group []string
+		for _, dir := range []string{"main", "backup"} {
+			path := filepath.Join(tmpDir, dir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			group = append(group, path)
+		}
+		groups = append(groups, group)
+	}
+	other := []string{filepath.Join(tmpDir, "other1.txt"), filepath.Join(tmpDir, "other2.txt")}
+	for _, path := range other {
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	groups = append(groups, other)
+
+	// "q *" is refused and asked again. "s *" skips the first three groups
+	// without asking, so "2" answers the last one.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe creation failed: %v", err)
+	}
+	defer r.Close()
+	if _, err := w.WriteString("q *\ns *\n2\n"); err != nil {
+		t.Fatalf("Failed to write to pipe: %v", err)
+	}
+	w.Close()
+	os.Stdin = r
+
+	handleDeletions(promptResults(t, groups...), actionOptions{})
+
+	for _, group := range groups[:3] {
+		assertFileExists(t, group[0], true)
+		assertFileExists(t, group[1], true)
+	}
+	assertFileExists(t, other[0], true)
+	assertFileExists(t, other[1], false)
+}
+
+// promptResults returns a closed channel holding a duplicate group for each
+// list of paths.
+func promptResults(t *testing.T, groups ...[]string) <-chan dupGroup {
+	t.Helper()
+	results := make(chan dupGroup, len(groups))
+	for _, paths := range groups {
+		g, err := newDupGroup(4, "", paths)
+		if err != nil {
+			t.Fatalf("newDupGroup failed: %v", err)
+		}
+		results <- g
+	}
+	close(results)
+	return results
+}
+
 func assertFileExists(t *testing.T, path string, shouldExist bool) {
 	t.Helper()
 	_, err := os.Stat(path)
@@ -97,6 +282,62 @@ func TestParseDeleteInput(t *testing.T) {
 			expected:    nil,
 			expectedErr: false,
 		},
+		{
+			name:        "keep only one file",
+			input:       "k 2",
+			max:         3,
+			expected:    []int{0, 2},
+			expectedErr: false,
+		},
+		{
+			name:        "keep only an out-of-range file",
+			input:       "k 4",
+			max:         3,
+			expected:    nil,
+			expectedErr: true,
+		},
+		{
+			name:        "double space between numbers",
+			input:       "1  3",
+			max:         3,
+			expected:    []int{0, 2},
+			expectedErr: false,
+		},
+		{
+			name:        "range",
+			input:       "2-4 1",
+			max:         5,
+			expected:    []int{1, 2, 3, 0},
+			expectedErr: false,
+		},
+		{
+			name:        "reversed range",
+			input:       "4-2",
+			max:         5,
+			expected:    nil,
+			expectedErr: true,
+		},
+		{
+			name:        "range past the end",
+			input:       "2-6",
+			max:         5,
+			expected:    nil,
+			expectedErr: true,
+		},
+		{
+			name:        "all but one file",
+			input:       "^3",
+			max:         4,
+			expected:    []int{0, 1, 3},
+			expectedErr: false,
+		},
+		{
+			name:        "all but the first file",
+			input:       "a",
+			max:         3,
+			expected:    []int{1, 2},
+			expectedErr: false,
+		},
 		{
 			name:        "input with out-of-range number",
 			input:       "0 2",
@@ -173,22 +414,26 @@ func TestDeleteFiles(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			var group []string
+			var group dupGroup
 			for _, file := range tt.files {
 				filePath := filepath.Join(tempDir, file)
-				group = append(group, filePath)
 				if !tt.expectedErr {
 					_, err := os.Create(filePath)
 					if err != nil {
 						t.Fatalf("Failed to create file: %v", err)
 					}
 				}
+				f, err := statDupFile(filePath)
+				if err != nil {
+					f = dupFile{path: filePath}
+				}
+				group.files = append(group.files, f)
 			}
 
-			deleteFiles(group, tt.indices)
+			deleteFiles(group, tt.indices, actionOptions{})
 
 			for _, idx := range tt.indices {
-				path := group[idx]
+				path := group.files[idx].path
 				_, err := os.Stat(path)
 				if err == nil {
 					t.Errorf("Failed to delete file %s: %v", path, err)
@@ -197,3 +442,28 @@ func TestDeleteFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteFilesQuarantine(t *testing.T) {
+	tempDir := t.TempDir()
+	quarantineDir := filepath.Join(tempDir, "quarantine")
+
+	group := []string{filepath.Join(tempDir, "file1.txt"), filepath.Join(tempDir, "file2.txt")}
+	for _, path := range group {
+		if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+	g, err := newDupGroup(4, "", group)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deleteFiles(g, []int{1}, actionOptions{quarantineDir: quarantineDir})
+
+	assertFileExists(t, group[0], true)
+	assertFileExists(t, group[1], false)
+	entries, err := readQuarantineManifest(quarantineDir)
+	if err != nil || len(entries) != 1 || entries[0].Original != group[1] {
+		t.Errorf("expected %s in the quarantine manifest, got: %+v, %v", group[1], entries, err)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// quarantineManifest is the file inside a quarantine directory that records
+// where every quarantined file came from, one JSON object per line.
+const quarantineManifest = "manifest.jsonl"
+
+// quarantineEntry is a line of the quarantine manifest.
+type quarantineEntry struct {
+	Time        time.Time `json:"time"`
+	Original    string    `json:"original"`
+	Quarantined string    `json:"quarantined"`
+	Size        int64     `json:"size"`
+}
+
+// quarantineFile moves path into the quarantine directory dir and records it
+// in the manifest. Files are stored under dir/files, mirroring their path
+// relative to root, the scanned directory they were found in, or their
+// absolute path if root is empty or does not contain them. A file gets a
+// numeric suffix if another one was quarantined to the same place before.
+func quarantineFile(dir, root, path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	fi, err := os.Lstat(abs)
+	if err != nil {
+		return "", err
+	}
+
+	rel := abs[len(filepath.VolumeName(abs)):]
+	if root != "" && isInside(abs, root) {
+		if rel, err = filepath.Rel(root, abs); err != nil {
+			return "", err
+		}
+	}
+	dst := filepath.Join(dir, "files", rel)
+	for i := 1; ; i++ {
+		_, err := os.Lstat(dst)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if i == 10000 {
+			return "", fmt.Errorf("cannot quarantine %s: no free name in %s", abs, filepath.Dir(dst))
+		}
+		dst = filepath.Join(dir, "files", rel+"."+strconv.Itoa(i))
+	}
+
+	if err := moveFile(abs, dst); err != nil {
+		return "", err
+	}
+
+	entry := quarantineEntry{Time: time.Now(), Original: abs, Quarantined: dst, Size: fi.Size()}
+	if err := appendQuarantineEntry(dir, entry); err != nil {
+		return dst, fmt.Errorf("moved %s to %s but failed to record it: %w", abs, dst, err)
+	}
+	return dst, nil
+}
+
+func appendQuarantineEntry(dir string, entry quarantineEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, quarantineManifest), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readQuarantineManifest(dir string) ([]quarantineEntry, error) {
+	f, err := os.Open(filepath.Join(dir, quarantineManifest))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []quarantineEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry quarantineEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid quarantine manifest: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// writeQuarantineManifest replaces the manifest of dir with entries.
+func writeQuarantineManifest(dir string, entries []quarantineEntry) error {
+	return replaceWith(filepath.Join(dir, quarantineManifest), func(tmp string) error {
+		f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		w := bufio.NewWriter(f)
+		enc := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := enc.Encode(entry); err != nil {
+				f.Close()
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+// restoreQuarantine moves every file in the quarantine directory dir back to
+// its original path and drops it from the manifest. Files whose original path
+// is taken again are left in quarantine and reported in the returned error.
+// Entries whose file is already gone, restored by undo for instance, are
+// dropped.
+func restoreQuarantine(dir string) (int, error) {
+	return processQuarantine(dir, func(entry quarantineEntry) error {
+		if _, err := os.Lstat(entry.Quarantined); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if _, err := os.Lstat(entry.Original); err == nil {
+			return fmt.Errorf("cannot restore %s: file exists", entry.Original)
+		}
+		return moveFile(entry.Quarantined, entry.Original)
+	})
+}
+
+// purgeQuarantine permanently deletes every file in the quarantine directory
+// dir and drops it from the manifest.
+func purgeQuarantine(dir string) (int, error) {
+	return processQuarantine(dir, func(entry quarantineEntry) error {
+		err := os.Remove(entry.Quarantined)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	})
+}
+
+// processQuarantine calls fn for every manifest entry of dir and rewrites the
+// manifest with the entries fn failed on. It returns how many entries
+// succeeded and the errors of the others.
+func processQuarantine(dir string, fn func(quarantineEntry) error) (int, error) {
+	entries, err := readQuarantineManifest(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var remaining []quarantineEntry
+	var errs []error
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			remaining = append(remaining, entry)
+			errs = append(errs, err)
+		}
+	}
+
+	if len(entries) > 0 {
+		if err := writeQuarantineManifest(dir, remaining); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return len(entries) - len(remaining), errors.Join(errs...)
+}
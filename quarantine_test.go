@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQuarantineRestore(t *testing.T) {
+	tempDir := t.TempDir()
+	quarantineDir := filepath.Join(tempDir, "quarantine")
+	path := filepath.Join(tempDir, "data", "dup.txt")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Quarantine a file from the same path twice, the second copy must not
+	// overwrite the first.
+	var quarantined []string
+	for _, content := range []string{"first", "second"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		dst, err := quarantineFile(quarantineDir, tempDir, path)
+		if err != nil {
+			t.Fatalf("quarantineFile failed: %v", err)
+		}
+		assertFileExists(t, path, false)
+		quarantined = append(quarantined, dst)
+	}
+
+	if quarantined[0] == quarantined[1] {
+		t.Fatalf("both files were quarantined to %s", quarantined[0])
+	}
+	if expected := filepath.Join(quarantineDir, "files", "data", "dup.txt"); quarantined[0] != expected {
+		t.Errorf("expected the path relative to the root to be mirrored at %s, got: %s", expected, quarantined[0])
+	}
+	for i, content := range []string{"first", "second"} {
+		b, err := os.ReadFile(quarantined[i])
+		if err != nil || string(b) != content {
+			t.Errorf("expected %s to contain %q, got: %q, %v", quarantined[i], content, b, err)
+		}
+	}
+
+	entries, err := readQuarantineManifest(quarantineDir)
+	if err != nil {
+		t.Fatalf("readQuarantineManifest failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 manifest entries, got: %d", len(entries))
+	}
+	if entries[0].Original != path || entries[0].Quarantined != quarantined[0] || entries[0].Size != int64(len("first")) {
+		t.Errorf("unexpected manifest entry: %+v", entries[0])
+	}
+
+	// Only one of the two files can go back to the original path.
+	n, err := restoreQuarantine(quarantineDir)
+	if n != 1 || err == nil {
+		t.Errorf("expected 1 file restored and an error, got: %d, %v", n, err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil || string(b) != "first" {
+		t.Errorf("expected restored file to contain %q, got: %q, %v", "first", b, err)
+	}
+
+	entries, err = readQuarantineManifest(quarantineDir)
+	if err != nil || len(entries) != 1 || entries[0].Quarantined != quarantined[1] {
+		t.Fatalf("expected the second file to remain in the manifest, got: %+v, %v", entries, err)
+	}
+
+	n, err = purgeQuarantine(quarantineDir)
+	if n != 1 || err != nil {
+		t.Errorf("expected 1 file purged, got: %d, %v", n, err)
+	}
+	assertFileExists(t, quarantined[1], false)
+
+	entries, err = readQuarantineManifest(quarantineDir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("expected an empty manifest, got: %+v, %v", entries, err)
+	}
+}
+
+func TestQuarantineFileUnderFile(t *testing.T) {
+	tempDir := t.TempDir()
+	quarantineDir := filepath.Join(tempDir, "quarantine")
+	path := filepath.Join(tempDir, "data", "x", "y")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("dup"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A file quarantined before sits where the directory of path would be.
+	if err := os.MkdirAll(filepath.Join(quarantineDir, "files"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(quarantineDir, "files", "x"), []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if dst, err := quarantineFile(quarantineDir, filepath.Join(tempDir, "data"), path); err == nil {
+		t.Errorf("expected an error, got the file quarantined to %s", dst)
+	}
+	assertFileExists(t, path, true)
+}
+
+func TestRestoreQuarantineEmpty(t *testing.T) {
+	n, err := restoreQuarantine(t.TempDir())
+	if n != 0 || err != nil {
+		t.Errorf("expected nothing to restore, got: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,7 @@
+package main
+
+import "errors"
+
+// errReflinkUnsupported is returned by reflinkFile when the platform or the
+// filesystem holding the files cannot share extents between files.
+var errReflinkUnsupported = errors.New("reflinks are not supported on this filesystem; use btrfs or XFS, or another action")
@@ -0,0 +1,95 @@
+//go:build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// dedupeChunk is how much data a single FIDEDUPERANGE call is asked to share.
+// Filesystems cap the length of one request, btrfs at 16 MiB.
+const dedupeChunk = 16 * 1024 * 1024
+
+// reflinkFile makes dup share its data extents with keep using the
+// FIDEDUPERANGE ioctl and returns the number of bytes deduplicated. Both
+// paths stay independent files. The kernel locks both files and compares
+// them byte by byte before sharing anything, so a file modified since the
+// scan is never touched.
+func reflinkFile(dup, keep string) (int64, error) {
+	src, err := os.Open(keep)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(dup, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrPermission) {
+		// Since Linux 4.19 the owner may dedupe into a file opened read-only.
+		dst, err = os.Open(dup)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	srcInfo, err := src.Stat()
+	if err != nil {
+		return 0, err
+	}
+	dstInfo, err := dst.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if !srcInfo.Mode().IsRegular() || !dstInfo.Mode().IsRegular() {
+		return 0, fmt.Errorf("cannot reflink %s to %s: not regular files", dup, keep)
+	}
+	if srcInfo.Size() != dstInfo.Size() {
+		return 0, fmt.Errorf("cannot reflink %s to %s: content differs", dup, keep)
+	}
+
+	var deduped int64
+	size := uint64(srcInfo.Size())
+	for offset := uint64(0); offset < size; {
+		r := unix.FileDedupeRange{
+			Src_offset: offset,
+			Src_length: min(dedupeChunk, size-offset),
+			Info: []unix.FileDedupeRangeInfo{{
+				Dest_fd:     int64(dst.Fd()),
+				Dest_offset: offset,
+			}},
+		}
+		if err := unix.IoctlFileDedupeRange(int(src.Fd()), &r); err != nil {
+			return deduped, dedupeError(dup, keep, err)
+		}
+
+		info := r.Info[0]
+		switch {
+		case info.Status == unix.FILE_DEDUPE_RANGE_DIFFERS:
+			return deduped, fmt.Errorf("cannot reflink %s to %s: content differs", dup, keep)
+		case info.Status < 0:
+			return deduped, dedupeError(dup, keep, unix.Errno(-info.Status))
+		case info.Bytes_deduped == 0:
+			return deduped, fmt.Errorf("cannot reflink %s to %s: no progress at offset %d", dup, keep, offset)
+		}
+		deduped += int64(info.Bytes_deduped)
+		offset += info.Bytes_deduped
+	}
+	return deduped, nil
+}
+
+// dedupeError turns the errors FIDEDUPERANGE reports for filesystems without
+// extent sharing into errReflinkUnsupported.
+func dedupeError(dup, keep string, err error) error {
+	switch {
+	case errors.Is(err, unix.EOPNOTSUPP), errors.Is(err, unix.ENOTTY), errors.Is(err, unix.EINVAL):
+		return fmt.Errorf("cannot reflink %s to %s: %w", dup, keep, errReflinkUnsupported)
+	case errors.Is(err, unix.EXDEV):
+		return fmt.Errorf("cannot reflink %s to %s: files are on different filesystems", dup, keep)
+	default:
+		return fmt.Errorf("cannot reflink %s to %s: %w", dup, keep, err)
+	}
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// reflinkTestDir returns a directory for reflink tests. Set
+// DUGO_REFLINK_TEST_DIR to a directory on btrfs or XFS, e.g. a loopback
+// mounted image, to exercise the ioctl; otherwise the temporary directory is
+// used and the tests skip if it does not support reflinks.
+func reflinkTestDir(t *testing.T) string {
+	t.Helper()
+	if dir := os.Getenv("DUGO_REFLINK_TEST_DIR"); dir != "" {
+		dir, err := os.MkdirTemp(dir, "dugo-reflink-")
+		if err != nil {
+			t.Fatalf("Failed to create test directory: %v", err)
+		}
+		t.Cleanup(func() { os.RemoveAll(dir) })
+		return dir
+	}
+	return t.TempDir()
+}
+
+func TestReflinkFile(t *testing.T) {
+	tempDir := reflinkTestDir(t)
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	keep := filepath.Join(tempDir, "keep.bin")
+	dup := filepath.Join(tempDir, "dup.bin")
+	other := filepath.Join(tempDir, "other.bin")
+	for _, path := range []string{keep, dup, other} {
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	n, err := reflinkFile(dup, keep)
+	if errors.Is(err, errReflinkUnsupported) {
+		t.Skipf("filesystem of %s does not support reflinks", tempDir)
+	}
+	if err != nil {
+		t.Fatalf("reflinkFile failed: %v", err)
+	}
+	if n != int64(len(content)) {
+		t.Errorf("expected %d bytes deduplicated, got: %d", len(content), n)
+	}
+
+	b, err := os.ReadFile(dup)
+	if err != nil || !bytes.Equal(b, content) {
+		t.Errorf("content of %s changed", dup)
+	}
+	keepInfo, err1 := os.Stat(keep)
+	dupInfo, err2 := os.Stat(dup)
+	if err1 != nil || err2 != nil || os.SameFile(keepInfo, dupInfo) {
+		t.Errorf("%s and %s must stay independent files", dup, keep)
+	}
+
+	changed := bytes.Clone(content)
+	changed[len(changed)-1] = 'X'
+	if err := os.WriteFile(other, changed, 0644); err != nil {
+		t.Fatalf("Failed to modify test file: %v", err)
+	}
+	if _, err := reflinkFile(other, keep); err == nil {
+		t.Error("expected an error deduplicating files with different content, got nil")
+	}
+}
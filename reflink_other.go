@@ -0,0 +1,10 @@
+//go:build !linux
+
+package main
+
+import "fmt"
+
+// reflinkFile is only implemented on Linux.
+func reflinkFile(dup, keep string) (int64, error) {
+	return 0, fmt.Errorf("cannot reflink %s to %s: %w", dup, keep, errReflinkUnsupported)
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// reporter writes duplicate groups in a particular output format.
+type reporter interface {
+	writeHeader() error
+	writeGroup(idx int, g dupGroup) error
+	writeFooter() error
+}
+
+// reportOptions holds the settings that only some output formats use.
+type reportOptions struct {
+	// groupSep is written after each group by the print0 format. It defaults
+	// to a NUL byte, so groups end with a double NUL.
+	groupSep string
+	// template is the text/template source used by the template format.
+	template string
+	// action is what generated scripts do with duplicates.
+	action string
+	// actionOpts configures the actions in generated scripts.
+	actionOpts actionOptions
+}
+
+// outputFormat returns the output format chosen by -format, -print0 and
+// -template. formatSet tells whether -format was given; it cannot name
+// another format than -print0, and -template excludes both.
+func outputFormat(format string, formatSet, print0, template bool) (string, error) {
+	switch {
+	case template && print0:
+		return "", fmt.Errorf("-template cannot be combined with -print0")
+	case template && formatSet:
+		return "", fmt.Errorf("-template cannot be combined with -format=%s", format)
+	case template:
+		return "template", nil
+	case !print0:
+		return format, nil
+	case formatSet && format != "print0":
+		return "", fmt.Errorf("-print0 cannot be combined with -format=%s", format)
+	}
+	return "print0", nil
+}
+
+// readTemplate returns the content of the file named by arg, or arg itself
+// when it is an inline template: it contains an action, or names no file
+// and does not look like a path.
+func readTemplate(arg string) (string, error) {
+	b, err := os.ReadFile(arg)
+	switch {
+	case err == nil:
+		return string(b), nil
+	case strings.Contains(arg, "{{"):
+		return arg, nil
+	case errors.Is(err, fs.ErrNotExist) && !strings.ContainsRune(arg, '/') &&
+		!strings.ContainsRune(arg, filepath.Separator) && filepath.Ext(arg) == "":
+		return arg, nil
+	}
+	return "", err
+}
+
+func newReporter(format string, w io.Writer, opts reportOptions) (reporter, error) {
+	switch format {
+	case "", "default":
+		return &defaultReporter{w: w}, nil
+	case "fdupes":
+		return &fdupesReporter{w: w}, nil
+	case "csv":
+		return &csvReporter{w: csv.NewWriter(w)}, nil
+	case "print0":
+		sep := opts.groupSep
+		if sep == "" {
+			sep = "\x00"
+		}
+		return &print0Reporter{w: w, sep: sep}, nil
+	case "template":
+		return newTemplateReporter(w, opts.template)
+	case "json":
+		return &jsonReporter{w: w}, nil
+	case "html":
+		return &htmlReporter{w: w}, nil
+	case "sh":
+		return newScriptReporter(w, opts.action, opts.actionOpts)
+	default:
+		return nil, fmt.Errorf("unknown output format: %q", format)
+	}
+}
+
+// report feeds every group received on results to r, numbering groups from 1
+// in arrival order.
+func report(r reporter, results <-chan dupGroup) error {
+	if err := r.writeHeader(); err != nil {
+		return err
+	}
+	idx := 0
+	for g := range results {
+		idx++
+		if err := r.writeGroup(idx, g); err != nil {
+			return err
+		}
+	}
+	return r.writeFooter()
+}
+
+type defaultReporter struct {
+	w io.Writer
+}
+
+func (r *defaultReporter) writeHeader() error { return nil }
+
+func (r *defaultReporter) writeGroup(idx int, g dupGroup) error {
+	_, err := fmt.Fprintln(r.w, "Equal files:", g.paths())
+	return err
+}
+
+func (r *defaultReporter) writeFooter() error { return nil }
+
+// fdupesReporter mimics the output of fdupes and jdupes: one path per line,
+// with a blank line after each group.
+type fdupesReporter struct {
+	w io.Writer
+}
+
+func (r *fdupesReporter) writeHeader() error { return nil }
+
+func (r *fdupesReporter) writeGroup(idx int, g dupGroup) error {
+	for _, f := range g.files {
+		if _, err := fmt.Fprintln(r.w, f.path); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(r.w)
+	return err
+}
+
+func (r *fdupesReporter) writeFooter() error { return nil }
+
+type csvReporter struct {
+	w *csv.Writer
+}
+
+func (r *csvReporter) writeHeader() error {
+	return r.w.Write([]string{"group", "size", "hash", "path", "mtime", "inode"})
+}
+
+func (r *csvReporter) writeGroup(idx int, g dupGroup) error {
+	for _, f := range g.files {
+		err := r.w.Write([]string{
+			strconv.Itoa(idx),
+			strconv.FormatInt(g.size, 10),
+			g.hash,
+			f.path,
+			f.mtime.Format(time.RFC3339),
+			strconv.FormatUint(f.sys.ino, 10),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *csvReporter) writeFooter() error {
+	r.w.Flush()
+	return r.w.Error()
+}
+
+// print0Reporter writes every path terminated by a NUL byte, like
+// find -print0, so the output can be fed to xargs -0 whatever characters the
+// paths contain.
+type print0Reporter struct {
+	w   io.Writer
+	sep string
+}
+
+func (r *print0Reporter) writeHeader() error { return nil }
+
+func (r *print0Reporter) writeGroup(idx int, g dupGroup) error {
+	for _, f := range g.files {
+		if _, err := io.WriteString(r.w, f.path+"\x00"); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(r.w, r.sep)
+	return err
+}
+
+func (r *print0Reporter) writeFooter() error { return nil }
+
+// templateGroup is the data a user template is executed with for each group.
+type templateGroup struct {
+	Index int
+	Size  int64
+	Hash  string
+	Paths []string
+	Files []templateFile
+}
+
+type templateFile struct {
+	Path  string
+	Mtime time.Time
+	Inode uint64
+}
+
+// templateSummary is the data the optional "footer" template is executed
+// with once all groups have been written.
+type templateSummary struct {
+	Groups      int
+	Files       int
+	Reclaimable int64
+}
+
+// templateReporter executes a user supplied text/template for every group.
+// If the template defines "header" or "footer" templates they are executed
+// before the first and after the last group respectively.
+type templateReporter struct {
+	w       io.Writer
+	tmpl    *template.Template
+	summary templateSummary
+}
+
+func newTemplateReporter(w io.Writer, text string) (*templateReporter, error) {
+	if text == "" {
+		return nil, fmt.Errorf("template format requires a template")
+	}
+	tmpl, err := template.New("group").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return &templateReporter{w: w, tmpl: tmpl}, nil
+}
+
+func (r *templateReporter) writeHeader() error {
+	if r.tmpl.Lookup("header") == nil {
+		return nil
+	}
+	return r.tmpl.ExecuteTemplate(r.w, "header", nil)
+}
+
+func (r *templateReporter) writeGroup(idx int, g dupGroup) error {
+	data := templateGroup{
+		Index: idx,
+		Size:  g.size,
+		Hash:  g.hash,
+		Paths: g.paths(),
+		Files: make([]templateFile, len(g.files)),
+	}
+	for i, f := range g.files {
+		data.Files[i] = templateFile{Path: f.path, Mtime: f.mtime, Inode: f.sys.ino}
+	}
+
+	r.summary.Groups++
+	r.summary.Files += len(g.files)
+	r.summary.Reclaimable += g.size * int64(len(g.files)-1)
+
+	return r.tmpl.Execute(r.w, data)
+}
+
+func (r *templateReporter) writeFooter() error {
+	if r.tmpl.Lookup("footer") == nil {
+		return nil
+	}
+	return r.tmpl.ExecuteTemplate(r.w, "footer", r.summary)
+}
+
+// jsonGroup is the structured form of a duplicate group shared by the json
+// and html formats.
+type jsonGroup struct {
+	Group       int        `json:"group"`
+	Size        int64      `json:"size"`
+	Hash        string     `json:"hash"`
+	Reclaimable int64      `json:"reclaimable"`
+	Files       []jsonFile `json:"files"`
+}
+
+type jsonFile struct {
+	Path  string    `json:"path"`
+	Mtime time.Time `json:"mtime"`
+	Inode uint64    `json:"inode"`
+}
+
+func newJSONGroup(idx int, g dupGroup) jsonGroup {
+	jg := jsonGroup{
+		Group:       idx,
+		Size:        g.size,
+		Hash:        g.hash,
+		Reclaimable: g.size * int64(len(g.files)-1),
+		Files:       make([]jsonFile, len(g.files)),
+	}
+	for i, f := range g.files {
+		jg.Files[i] = jsonFile{Path: f.path, Mtime: f.mtime, Inode: f.sys.ino}
+	}
+	return jg
+}
+
+// jsonReporter writes a JSON array with one object per group, streaming
+// groups as they arrive.
+type jsonReporter struct {
+	w     io.Writer
+	count int
+}
+
+func (r *jsonReporter) writeHeader() error {
+	_, err := io.WriteString(r.w, "[")
+	return err
+}
+
+func (r *jsonReporter) writeGroup(idx int, g dupGroup) error {
+	b, err := json.MarshalIndent(newJSONGroup(idx, g), "  ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := "\n  "
+	if r.count > 0 {
+		sep = ",\n  "
+	}
+	r.count++
+	_, err = io.WriteString(r.w, sep+string(b))
+	return err
+}
+
+func (r *jsonReporter) writeFooter() error {
+	_, err := io.WriteString(r.w, "\n]\n")
+	return err
+}
+
+// formatBytes renders n using binary units, e.g. "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
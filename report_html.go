@@ -0,0 +1,132 @@
+package main
+
+import (
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// htmlReporter buffers every group and writes a self-contained HTML page once
+// the scan is complete, since groups are listed by reclaimable space.
+type htmlReporter struct {
+	w      io.Writer
+	groups []jsonGroup
+}
+
+type htmlReport struct {
+	Generated   time.Time
+	Groups      []jsonGroup
+	Dirs        []htmlDirTotal
+	Files       int
+	Reclaimable int64
+}
+
+// htmlDirTotal sums up the duplicate files found directly inside a directory.
+type htmlDirTotal struct {
+	Dir   string
+	Files int
+	Bytes int64
+}
+
+func (r *htmlReporter) writeHeader() error { return nil }
+
+func (r *htmlReporter) writeGroup(idx int, g dupGroup) error {
+	r.groups = append(r.groups, newJSONGroup(idx, g))
+	return nil
+}
+
+func (r *htmlReporter) writeFooter() error {
+	data := htmlReport{Generated: time.Now(), Groups: r.groups}
+
+	sort.SliceStable(data.Groups, func(i, j int) bool {
+		return data.Groups[i].Reclaimable > data.Groups[j].Reclaimable
+	})
+
+	dirs := make(map[string]*htmlDirTotal)
+	for _, g := range data.Groups {
+		data.Files += len(g.Files)
+		data.Reclaimable += g.Reclaimable
+		for _, f := range g.Files {
+			dir := filepath.Dir(f.Path)
+			total, ok := dirs[dir]
+			if !ok {
+				total = &htmlDirTotal{Dir: dir}
+				dirs[dir] = total
+			}
+			total.Files++
+			total.Bytes += g.Size
+		}
+	}
+	for _, total := range dirs {
+		data.Dirs = append(data.Dirs, *total)
+	}
+	sort.Slice(data.Dirs, func(i, j int) bool {
+		if data.Dirs[i].Bytes != data.Dirs[j].Bytes {
+			return data.Dirs[i].Bytes > data.Dirs[j].Bytes
+		}
+		return data.Dirs[i].Dir < data.Dirs[j].Dir
+	})
+
+	return htmlTemplate.Execute(r.w, data)
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"bytes": formatBytes,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>dugo duplicate report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.2em 1em 0.2em 0; }
+td.num, th.num { text-align: right; }
+details { border: 1px solid #ddd; border-radius: 4px; margin: 0.4em 0; padding: 0.4em 0.8em; }
+summary { cursor: pointer; }
+ul { margin: 0.4em 0; }
+li { font-family: monospace; }
+.muted { color: #777; }
+#search { width: 30em; padding: 0.3em; margin-bottom: 1em; }
+</style>
+</head>
+<body>
+<h1>Duplicate files</h1>
+<p>{{len .Groups}} groups, {{.Files}} files, {{bytes .Reclaimable}} reclaimable.
+<span class="muted">Generated {{.Generated.Format "2006-01-02 15:04:05"}}.</span></p>
+
+<h2>Directories</h2>
+<table>
+<tr><th>Directory</th><th class="num">Duplicate files</th><th class="num">Size</th></tr>
+{{range .Dirs}}<tr><td>{{.Dir}}</td><td class="num">{{.Files}}</td><td class="num">{{bytes .Bytes}}</td></tr>
+{{end}}</table>
+
+<h2>Groups</h2>
+<input id="search" type="search" placeholder="Filter by path">
+<div id="groups">
+{{range .Groups}}<details class="group">
+<summary>{{len .Files}} files of {{bytes .Size}}, {{bytes .Reclaimable}} reclaimable <span class="muted">{{.Hash}}</span></summary>
+<ul>
+{{range .Files}}<li>{{.Path}} <span class="muted">{{.Mtime.Format "2006-01-02 15:04:05"}}</span></li>
+{{end}}</ul>
+</details>
+{{end}}</div>
+
+<script>
+document.getElementById("search").addEventListener("input", function () {
+  var q = this.value.toLowerCase();
+  document.querySelectorAll("#groups details").forEach(function (d) {
+    var match = q === "" || d.textContent.toLowerCase().indexOf(q) !== -1;
+    d.style.display = match ? "" : "none";
+    if (q !== "") {
+      d.open = match;
+    }
+  });
+});
+</script>
+</body>
+</html>
+`))
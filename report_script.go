@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// scriptReporter writes a POSIX shell script that, when run, keeps one file
+// of every group, chosen by the keep rules, and removes or links the others.
+// Nothing is touched while dugo runs, so the script can be reviewed and
+// edited first.
+type scriptReporter struct {
+	w           io.Writer
+	action      string
+	opts        actionOptions
+	groups      int
+	reclaimable int64
+}
+
+func newScriptReporter(w io.Writer, action string, opts actionOptions) (*scriptReporter, error) {
+	switch action {
+	case "", "delete":
+		action = "delete"
+	case "hardlink", "symlink":
+	default:
+		return nil, fmt.Errorf("action %q is not supported in scripts", action)
+	}
+	// Scripts remove files with rm, which would silently delete what the
+	// user asked to keep recoverable.
+	if opts.trash || opts.quarantineDir != "" {
+		return nil, fmt.Errorf("-trash and -quarantine are not supported in scripts")
+	}
+	return &scriptReporter{w: w, action: action, opts: opts}, nil
+}
+
+const scriptHeader = `#!/bin/sh
+# Generated by dugo on %s.
+#
+# Review this script before running it. Each group below keeps one file and
+# applies an action to the others. Before every action the script checks that
+# both files are still regular files of the size recorded during the scan,
+# and skips the action otherwise.
+
+set -u
+
+check_size() {
+	[ -f "$1" ] && [ ! -L "$1" ] && [ "$(wc -c < "$1" | tr -d ' ')" = "$2" ]
+}
+
+# keep <path>
+keep() {
+	[ -f "$1" ] || echo "warning: kept file is missing: $1" >&2
+}
+
+# remove <duplicate> <kept> <size>
+remove() {
+	if check_size "$1" "$3" && check_size "$2" "$3"; then
+		rm -f -- "$1" && echo "removed: $1"
+	else
+		echo "skipped: $1 (file missing or size changed)" >&2
+	fi
+}
+
+# hardlink <duplicate> <kept> <size>
+hardlink() {
+	if check_size "$1" "$3" && check_size "$2" "$3"; then
+		tmp="$1.dugo-$$"
+		ln -- "$2" "$tmp" && mv -f -- "$tmp" "$1" && echo "linked: $1 -> $2"
+	else
+		echo "skipped: $1 (file missing or size changed)" >&2
+	fi
+}
+
+# symlink <duplicate> <kept> <target> <size>
+symlink() {
+	if check_size "$1" "$4" && check_size "$2" "$4"; then
+		tmp="$1.dugo-$$"
+		ln -s -- "$3" "$tmp" && mv -f -- "$tmp" "$1" && echo "linked: $1 -> $3"
+	else
+		echo "skipped: $1 (file missing or size changed)" >&2
+	fi
+}
+
+`
+
+func (r *scriptReporter) writeHeader() error {
+	_, err := fmt.Fprintf(r.w, scriptHeader, time.Now().Format(time.RFC3339))
+	return err
+}
+
+func (r *scriptReporter) writeGroup(idx int, g dupGroup) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Group %d: %d files of %s, hash %s\n", idx, len(g.files), formatBytes(g.size), g.hash)
+
+	keepIdx := r.opts.keep.choose(g)
+	kept := g.files[keepIdx].path
+	fmt.Fprintf(&b, "keep %s\n", shellQuote(kept))
+
+	if r.action == "symlink" {
+		if err := checkSymlinkTarget(kept, r.opts.removedFrom); err != nil {
+			// A newline in a path would end the comment.
+			msg := strings.ReplaceAll(fmt.Sprintf("cannot link to %s: %v", kept, err), "\n", `\n`)
+			fmt.Fprintf(&b, "# skipped: %s\n\n", msg)
+			r.groups++
+			_, err := io.WriteString(r.w, b.String())
+			return err
+		}
+	}
+
+	for i, f := range g.files {
+		if i == keepIdx {
+			continue
+		}
+		switch r.action {
+		case "delete":
+			fmt.Fprintf(&b, "remove %s %s %d\n", shellQuote(f.path), shellQuote(kept), g.size)
+		case "hardlink":
+			fmt.Fprintf(&b, "hardlink %s %s %d\n", shellQuote(f.path), shellQuote(kept), g.size)
+		case "symlink":
+			target, err := symlinkTarget(f.path, kept, r.opts.relativeSymlinks)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "symlink %s %s %s %d\n", shellQuote(f.path), shellQuote(kept), shellQuote(target), g.size)
+		}
+	}
+	b.WriteString("\n")
+
+	r.groups++
+	r.reclaimable += g.size * int64(len(g.files)-1)
+
+	_, err := io.WriteString(r.w, b.String())
+	return err
+}
+
+func (r *scriptReporter) writeFooter() error {
+	_, err := fmt.Fprintf(r.w, "# %d groups, %s reclaimable\n", r.groups, formatBytes(r.reclaimable))
+	return err
+}
+
+// shellQuote quotes s for a POSIX shell by wrapping it in single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain.txt", `'plain.txt'`},
+		{"with space.txt", `'with space.txt'`},
+		{"it's.txt", `'it'\''s.txt'`},
+		{"$(rm -rf /)", `'$(rm -rf /)'`},
+	}
+	for _, tt := range tests {
+		if got := shellQuote(tt.input); got != tt.expected {
+			t.Errorf("shellQuote(%q): expected %s, got %s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestScriptReporter(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("generated scripts need a POSIX shell")
+	}
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+
+	origVolatileDirs := volatileDirs
+	defer func() { volatileDirs = origVolatileDirs }()
+	volatileDirs = func() []string { return nil }
+
+	tests := []struct {
+		name   string
+		action string
+	}{
+		{"delete", "delete"},
+		{"hardlink", "hardlink"},
+		{"symlink", "symlink"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			paths := []string{
+				filepath.Join(tempDir, "keep.txt"),
+				filepath.Join(tempDir, "it's a copy.txt"),
+				filepath.Join(tempDir, "-changed.txt"),
+			}
+			for _, path := range paths {
+				if err := os.WriteFile(path, []byte("same content"), 0644); err != nil {
+					t.Fatalf("Failed to create test file: %v", err)
+				}
+			}
+
+			g, err := newDupGroup(int64(len("same content")), "hash", paths)
+			if err != nil {
+				t.Fatalf("newDupGroup failed: %v", err)
+			}
+
+			var script bytes.Buffer
+			r, err := newReporter("sh", &script, reportOptions{action: tt.action})
+			if err != nil {
+				t.Fatalf("newReporter failed: %v", err)
+			}
+			results := make(chan dupGroup, 1)
+			results <- g
+			close(results)
+			if err := report(r, results); err != nil {
+				t.Fatalf("report failed: %v", err)
+			}
+
+			// The file changes after the scan, so the script must leave it alone.
+			if err := os.WriteFile(paths[2], []byte("edited after the scan"), 0644); err != nil {
+				t.Fatalf("Failed to modify test file: %v", err)
+			}
+
+			scriptPath := filepath.Join(tempDir, "dedupe.sh")
+			if err := os.WriteFile(scriptPath, script.Bytes(), 0755); err != nil {
+				t.Fatalf("Failed to write script: %v", err)
+			}
+			out, err := exec.Command(sh, scriptPath).CombinedOutput()
+			if err != nil {
+				t.Fatalf("script failed: %v\n%s", err, out)
+			}
+			if !strings.Contains(string(out), "skipped: "+paths[2]) {
+				t.Errorf("expected the changed file to be skipped, output:\n%s", out)
+			}
+
+			b, err := os.ReadFile(paths[2])
+			if err != nil || string(b) != "edited after the scan" {
+				t.Errorf("changed file was modified by the script")
+			}
+
+			assertFileExists(t, paths[0], true)
+			switch tt.action {
+			case "delete":
+				assertFileExists(t, paths[1], false)
+			case "hardlink":
+				keep, err1 := os.Stat(paths[0])
+				dup, err2 := os.Stat(paths[1])
+				if err1 != nil || err2 != nil || !os.SameFile(keep, dup) {
+					t.Errorf("%s is not a hardlink to %s", paths[1], paths[0])
+				}
+			case "symlink":
+				target, err := os.Readlink(paths[1])
+				if err != nil || target != paths[0] {
+					t.Errorf("%s is not a symlink to %s: %q, %v", paths[1], paths[0], target, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewScriptReporterRecoverableRemoval(t *testing.T) {
+	for _, opts := range []actionOptions{{trash: true}, {quarantineDir: "/quarantine"}} {
+		if _, err := newScriptReporter(&bytes.Buffer{}, "delete", opts); err == nil {
+			t.Errorf("expected an error for %+v, got nil", opts)
+		}
+	}
+}
+
+func TestScriptReporterUnsafeSymlinkTarget(t *testing.T) {
+	tempDir := t.TempDir()
+	origVolatileDirs := volatileDirs
+	defer func() { volatileDirs = origVolatileDirs }()
+	volatileDirs = func() []string { return []string{tempDir} }
+
+	g := dupGroup{size: 4, hash: "hash", files: []dupFile{
+		{path: filepath.Join(tempDir, "keep.txt")},
+		{path: filepath.Join(tempDir, "dup.txt")},
+	}}
+	var script bytes.Buffer
+	r, err := newReporter("sh", &script, reportOptions{action: "symlink"})
+	if err != nil {
+		t.Fatalf("newReporter failed: %v", err)
+	}
+	results := make(chan dupGroup, 1)
+	results <- g
+	close(results)
+	if err := report(r, results); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	if out := script.String(); strings.Contains(out, "\nsymlink ") || !strings.Contains(out, "# skipped: cannot link to "+g.files[0].path) {
+		t.Errorf("expected the group to be skipped, got:\n%s", out)
+	}
+}
+
+func TestNewScriptReporterUnsupportedAction(t *testing.T) {
+	if _, err := newReporter("sh", &bytes.Buffer{}, reportOptions{action: "explode"}); err == nil {
+		t.Error("expected an error for an unsupported action, got nil")
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testGroups() []dupGroup {
+	mtime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []dupGroup{
+		{
+			size: 11,
+			hash: "aaaa",
+			files: []dupFile{
+				{path: "/data/a.txt", mtime: mtime, sys: sysInfo{ino: 10}},
+				{path: "/data/copy of a.txt", mtime: mtime, sys: sysInfo{ino: 11}},
+			},
+		},
+		{
+			size: 5,
+			hash: "bbbb",
+			files: []dupFile{
+				{path: "/data/b.txt", mtime: mtime, sys: sysInfo{ino: 20}},
+				{path: "/backup/b.txt", mtime: mtime, sys: sysInfo{ino: 21}},
+				{path: "/backup/old/b.txt", mtime: mtime, sys: sysInfo{ino: 22}},
+			},
+		},
+	}
+}
+
+func runReport(t *testing.T, format string, opts reportOptions, groups []dupGroup) string {
+	t.Helper()
+	var buf bytes.Buffer
+	r, err := newReporter(format, &buf, opts)
+	if err != nil {
+		t.Fatalf("newReporter(%q) failed: %v", format, err)
+	}
+
+	results := make(chan dupGroup, len(groups))
+	for _, g := range groups {
+		results <- g
+	}
+	close(results)
+
+	if err := report(r, results); err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestDefaultReporter(t *testing.T) {
+	got := runReport(t, "default", reportOptions{}, testGroups())
+	expected := "Equal files: [/data/a.txt /data/copy of a.txt]\n" +
+		"Equal files: [/data/b.txt /backup/b.txt /backup/old/b.txt]\n"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestFdupesReporter(t *testing.T) {
+	got := runReport(t, "fdupes", reportOptions{}, testGroups())
+	expected := "/data/a.txt\n/data/copy of a.txt\n\n" +
+		"/data/b.txt\n/backup/b.txt\n/backup/old/b.txt\n\n"
+	if got != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, got)
+	}
+}
+
+func TestCSVReporter(t *testing.T) {
+	got := runReport(t, "csv", reportOptions{}, testGroups())
+
+	records, err := csv.NewReader(bytes.NewBufferString(got)).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+
+	expected := [][]string{
+		{"group", "size", "hash", "path", "mtime", "inode"},
+		{"1", "11", "aaaa", "/data/a.txt", "2024-05-01T12:00:00Z", "10"},
+		{"1", "11", "aaaa", "/data/copy of a.txt", "2024-05-01T12:00:00Z", "11"},
+		{"2", "5", "bbbb", "/data/b.txt", "2024-05-01T12:00:00Z", "20"},
+		{"2", "5", "bbbb", "/backup/b.txt", "2024-05-01T12:00:00Z", "21"},
+		{"2", "5", "bbbb", "/backup/old/b.txt", "2024-05-01T12:00:00Z", "22"},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("expected %d records, got %d", len(expected), len(records))
+	}
+	for i := range expected {
+		for j := range expected[i] {
+			if records[i][j] != expected[i][j] {
+				t.Errorf("record %d field %d: expected %q, got %q", i, j, expected[i][j], records[i][j])
+			}
+		}
+	}
+}
+
+func TestPrint0Reporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		groupSep string
+		expected string
+	}{
+		{
+			name:     "default separator",
+			groupSep: "",
+			expected: "/data/a.txt\x00/data/copy of a.txt\x00\x00" +
+				"/data/b.txt\x00/backup/b.txt\x00/backup/old/b.txt\x00\x00",
+		},
+		{
+			name:     "custom separator",
+			groupSep: "\n",
+			expected: "/data/a.txt\x00/data/copy of a.txt\x00\n" +
+				"/data/b.txt\x00/backup/b.txt\x00/backup/old/b.txt\x00\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runReport(t, "print0", reportOptions{groupSep: tt.groupSep}, testGroups())
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    string
+		formatSet bool
+		print0    bool
+		template  bool
+		expected  string
+	}{
+		{"Format only", "csv", true, false, false, "csv"},
+		{"Print0 only", "default", false, true, false, "print0"},
+		{"Print0 with the same format", "print0", true, true, false, "print0"},
+		{"Print0 with another format", "json", true, true, false, ""},
+		{"Print0 with the default format given", "default", true, true, false, ""},
+		{"Template only", "default", false, false, true, "template"},
+		{"Template with a format", "json", true, false, true, ""},
+		{"Template with print0", "default", false, true, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := outputFormat(tt.format, tt.formatSet, tt.print0, tt.template)
+			if tt.expected == "" {
+				if err == nil {
+					t.Errorf("expected an error, got format %q", got)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("expected %q, got %q, %v", tt.expected, got, err)
+			}
+		})
+	}
+}
+
+func TestTemplateReporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "group only",
+			template: "{{.Index}} {{.Size}} {{join .Paths \",\"}}\n",
+			expected: "1 11 /data/a.txt,/data/copy of a.txt\n" +
+				"2 5 /data/b.txt,/backup/b.txt,/backup/old/b.txt\n",
+		},
+		{
+			name: "header and footer",
+			template: `{{define "header"}}BEGIN
+{{end}}{{define "footer"}}END {{.Groups}} {{.Files}} {{.Reclaimable}}
+{{end}}{{range .Files}}{{.Path}} {{.Inode}} {{.Mtime.Year}}
+{{end}}`,
+			expected: "BEGIN\n" +
+				"/data/a.txt 10 2024\n/data/copy of a.txt 11 2024\n" +
+				"/data/b.txt 20 2024\n/backup/b.txt 21 2024\n/backup/old/b.txt 22 2024\n" +
+				"END 2 5 21\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runReport(t, "template", reportOptions{template: tt.template}, testGroups())
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNewTemplateReporterErrors(t *testing.T) {
+	for _, text := range []string{"", "{{.Index"} {
+		if _, err := newReporter("template", &bytes.Buffer{}, reportOptions{template: text}); err == nil {
+			t.Errorf("expected an error for template %q, got nil", text)
+		}
+	}
+}
+
+func TestReadTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "group.tmpl")
+	if err := os.WriteFile(path, []byte("{{.Size}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		arg      string
+		expected string
+		wantErr  bool
+	}{
+		{"File", path, "{{.Size}}", false},
+		{"Inline template", "{{.Hash}}", "{{.Hash}}", false},
+		{"Inline text", "group", "group", false},
+		{"Missing file", filepath.Join(dir, "missing.tmpl"), "", true},
+		{"Missing file with an extension", "missing.tmpl", "", true},
+		{"Directory", dir, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTemplate(tt.arg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("expected %q, got %q, %v", tt.expected, got, err)
+			}
+		})
+	}
+}
+
+func TestJSONReporter(t *testing.T) {
+	for _, groups := range [][]dupGroup{testGroups(), nil} {
+		got := runReport(t, "json", reportOptions{}, groups)
+
+		var decoded []jsonGroup
+		if err := json.Unmarshal([]byte(got), &decoded); err != nil {
+			t.Fatalf("output is not valid JSON: %v\n%s", err, got)
+		}
+		if len(decoded) != len(groups) {
+			t.Fatalf("expected %d groups, got %d", len(groups), len(decoded))
+		}
+		for i, g := range groups {
+			expected := newJSONGroup(i+1, g)
+			if decoded[i].Group != expected.Group || decoded[i].Reclaimable != expected.Reclaimable {
+				t.Errorf("group %d: expected %+v, got %+v", i, expected, decoded[i])
+			}
+			if len(decoded[i].Files) != len(g.files) || decoded[i].Files[0].Path != g.files[0].path {
+				t.Errorf("group %d: unexpected files %+v", i, decoded[i].Files)
+			}
+		}
+	}
+}
+
+func TestHTMLReporter(t *testing.T) {
+	groups := testGroups()
+	groups[0].files[1].path = "/data/<script>.txt"
+	got := runReport(t, "html", reportOptions{}, groups)
+
+	if strings.Contains(got, "<script>.txt") {
+		t.Error("paths are not HTML escaped")
+	}
+	if strings.Contains(got, "http://") || strings.Contains(got, "https://") {
+		t.Error("report references external resources")
+	}
+
+	// The first group reclaims 11 bytes, the second 10, so the first is listed first.
+	first := strings.Index(got, "/data/a.txt")
+	second := strings.Index(got, "/backup/old/b.txt")
+	if first == -1 || second == -1 || first > second {
+		t.Errorf("groups are not sorted by reclaimable space")
+	}
+
+	if !strings.Contains(got, "<td>/backup</td><td class=\"num\">1</td><td class=\"num\">5 B</td>") {
+		t.Errorf("missing directory total for /backup")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n        int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024 * 1024, "5.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.expected {
+			t.Errorf("formatBytes(%d): expected %q, got %q", tt.n, tt.expected, got)
+		}
+	}
+}
+
+func TestNewReporterUnknownFormat(t *testing.T) {
+	if _, err := newReporter("xml", &bytes.Buffer{}, reportOptions{}); err == nil {
+		t.Error("expected an error for an unknown format, got nil")
+	}
+}
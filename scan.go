@@ -1,6 +1,7 @@
 package main
 
 import (
+	"log"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -8,12 +9,23 @@ import (
 
 type sameSizeFiles []string
 
-func scanDir(root string, ignoreNames map[string]struct{}, ignoreRegex *regexp.Regexp) (map[int64]sameSizeFiles, error) {
+// scanDir lists the files under root by size. Files and directories below
+// root that cannot be read are logged, counted in stats and skipped; only a
+// root that cannot be walked at all is an error.
+func scanDir(root string, ignoreNames map[string]struct{}, ignoreRegex *regexp.Regexp, stats *scanStats) (map[int64]sameSizeFiles, error) {
 	filesBySize := make(map[int64]sameSizeFiles)
 
 	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
-			return err
+			if path == root {
+				return err
+			}
+			log.Printf("Error: %v", err)
+			stats.errors.Add(1)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 
 		base := filepath.Base(path)
@@ -38,10 +50,13 @@ func scanDir(root string, ignoreNames map[string]struct{}, ignoreRegex *regexp.R
 
 		finfo, err := d.Info()
 		if err != nil {
-			return err
+			log.Printf("Error: %v", err)
+			stats.errors.Add(1)
+			return nil
 		}
 
 		filesBySize[finfo.Size()] = append(filesBySize[finfo.Size()], path)
+		stats.walked.Add(1)
 		return nil
 	})
 
@@ -50,3 +65,28 @@ func scanDir(root string, ignoreNames map[string]struct{}, ignoreRegex *regexp.R
 	}
 	return filesBySize, nil
 }
+
+// scanDirs scans every root like scanDir and merges the results. A file
+// reachable from several roots, e.g. because one root contains another, is
+// only listed once, under the first root in which it was found.
+func scanDirs(roots []string, ignoreNames map[string]struct{}, ignoreRegex *regexp.Regexp, stats *scanStats) (map[int64]sameSizeFiles, error) {
+	defer stats.walkDone.Store(true)
+	filesBySize := make(map[int64]sameSizeFiles)
+	seen := make(map[string]struct{})
+	for _, root := range roots {
+		m, err := scanDir(root, ignoreNames, ignoreRegex, stats)
+		if err != nil {
+			return nil, err
+		}
+		for size, files := range m {
+			for _, file := range files {
+				if _, ok := seen[file]; ok {
+					continue
+				}
+				seen[file] = struct{}{}
+				filesBySize[size] = append(filesBySize[size], file)
+			}
+		}
+	}
+	return filesBySize, nil
+}
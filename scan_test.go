@@ -204,7 +204,7 @@ func TestScanDir(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			filesBySize, err := scanDir(tc.root, tc.ignoreNames, tc.ignoreRegex)
+			filesBySize, err := scanDir(tc.root, tc.ignoreNames, tc.ignoreRegex, &scanStats{})
 
 			if tc.expectError {
 				if err == nil {
@@ -263,3 +263,73 @@ func TestScanDir(t *testing.T) {
 		})
 	}
 }
+
+func TestScanDirs(t *testing.T) {
+	tempDir := t.TempDir()
+
+	files := []string{"a/file1.txt", "a/nested/file2.txt", "b/file3.txt"}
+	for _, f := range files {
+		fullPath := filepath.Join(tempDir, f)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte("same"), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", fullPath, err)
+		}
+	}
+
+	roots := []string{
+		filepath.Join(tempDir, "a"),
+		filepath.Join(tempDir, "b"),
+		filepath.Join(tempDir, "a", "nested"),
+	}
+	var stats scanStats
+	result, err := scanDirs(roots, nil, nil, &stats)
+	if err != nil {
+		t.Fatalf("scanDirs failed: %v", err)
+	}
+
+	// The progress counts file2.txt once per root it was found in.
+	if !stats.walkDone.Load() || stats.walked.Load() != 4 {
+		t.Errorf("expected the walk to be done after 4 files, got: %v, %d", stats.walkDone.Load(), stats.walked.Load())
+	}
+	if len(result[4]) != 3 {
+		t.Errorf("expected 3 files of size 4, each listed once, got: %v", result[4])
+	}
+
+	if _, err := scanDirs(append(roots, filepath.Join(tempDir, "missing")), nil, nil, &scanStats{}); err == nil {
+		t.Error("expected an error for a missing root, got nil")
+	}
+}
+
+func TestScanDirUnreadableSubdir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can read any directory")
+	}
+	tempDir := t.TempDir()
+	locked := filepath.Join(tempDir, "locked")
+	if err := os.MkdirAll(locked, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(tempDir, "file1.txt"), filepath.Join(locked, "file2.txt")} {
+		if err := os.WriteFile(path, []byte("same"), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0755)
+
+	var stats scanStats
+	filesBySize, err := scanDir(tempDir, nil, nil, &stats)
+	if err != nil {
+		t.Fatalf("expected the scan to go on past %s, got: %v", locked, err)
+	}
+	if len(filesBySize[4]) != 1 || stats.errors.Load() != 1 {
+		t.Errorf("expected 1 file found and 1 error, got: %v, %d errors", filesBySize[4], stats.errors.Load())
+	}
+	if stats.exitCode() != exitPartial {
+		t.Errorf("expected exit code %d, got: %d", exitPartial, stats.exitCode())
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// markKept selects every file of the group at groupIdx except the one the
+// keep rules choose: the -keep rules if any were given, and the oldest file
+// otherwise.
+func (m model) markKept(groupIdx int) {
+	rules := m.actionOpts.keep
+	if len(rules) == 0 {
+		oldest, _ := parseKeepRule("oldest", nil)
+		rules = keepRules{oldest}
+	}
+	g := m.groups[groupIdx]
+	m.markAllBut(groupIdx, rules.choose(g), len(g.files))
+}
+
+// invertSelection selects the unselected files of the group at groupIdx and
+// unselects the others.
+func (m model) invertSelection(groupIdx int) {
+	inverted := make(map[int]struct{})
+	for i := range m.groups[groupIdx].files {
+		if _, selected := m.selected[groupIdx][i]; !selected {
+			inverted[i] = struct{}{}
+		}
+	}
+	m.selected[groupIdx] = inverted
+}
+
+// selectInDir selects every file inside dir, in every group. A group never
+// gets all of its files selected this way: if they are all inside dir, its
+// first file is left unselected, so that a copy is kept. It returns the
+// number of files newly selected.
+func (m model) selectInDir(dir string) int {
+	added := 0
+	for groupIdx, g := range m.groups {
+		var inDir []int
+		for i, f := range g.files {
+			if _, selected := m.selected[groupIdx][i]; !selected && isInside(f.path, dir) {
+				inDir = append(inDir, i)
+			}
+		}
+		if len(inDir) == 0 {
+			continue
+		}
+		if len(m.selected[groupIdx])+len(inDir) == len(g.files) {
+			inDir = inDir[1:]
+		}
+		if _, exists := m.selected[groupIdx]; !exists {
+			m.selected[groupIdx] = make(map[int]struct{})
+		}
+		for _, i := range inDir {
+			m.selected[groupIdx][i] = struct{}{}
+		}
+		added += len(inDir)
+	}
+	return added
+}
+
+// selectionTotals describes the selected files for the confirm dialog: how
+// many there are, in how many groups, their total size, and a warning if
+// some groups have every copy selected.
+func (m model) selectionTotals() string {
+	files, groups := 0, 0
+	var size int64
+	for groupIdx, sel := range m.selected {
+		if len(sel) == 0 || groupIdx >= len(m.groups) {
+			continue
+		}
+		files += len(sel)
+		groups++
+		size += m.groups[groupIdx].size * int64(len(sel))
+	}
+
+	totals := fmt.Sprintf("%d files in %d groups, %s in total", files, groups, formatBytes(size))
+	if whole := m.wholeGroups(); whole > 0 {
+		totals += "\n" + deleteStyle.Render(fmt.Sprintf("⚠ Every copy is selected in %d groups", whole))
+	}
+	return totals
+}
+
+// wholeGroups returns the number of groups with every file selected, which
+// the confirm dialog warns about. keepsNoCopy decides which actions on them
+// are refused.
+func (m model) wholeGroups() int {
+	whole := 0
+	for groupIdx, sel := range m.selected {
+		if groupIdx < len(m.groups) && len(sel) > 0 && len(sel) == len(m.groups[groupIdx].files) {
+			whole++
+		}
+	}
+	return whole
+}
+
+// currentDir returns the directory of the highlighted file.
+func (m model) currentDir() string {
+	return filepath.Dir(m.groups[m.currentGroup].files[m.currentFile].path)
+}
+
+// keepsNoCopy reports whether the pending action would delete every copy of
+// some group for good. Trashed and quarantined files can be restored, so
+// they only get the warning of selectionTotals.
+func (m model) keepsNoCopy() bool {
+	return m.confirmAction == "delete" && m.actionOpts.removeAction() == "delete" && m.wholeGroups() > 0
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// selectedIndices returns the sorted indices of the selected files of the
+// group at groupIdx.
+func selectedIndices(m model, groupIdx int) []int {
+	var indices []int
+	for i := range m.selected[groupIdx] {
+		indices = append(indices, i)
+	}
+	slices.Sort(indices)
+	return indices
+}
+
+func keyRune(r rune) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}}
+}
+
+func TestModelBulkSelection(t *testing.T) {
+	m := testModel(4, 3)
+	now := time.Now()
+	for i := range m.groups[0].files {
+		m.groups[0].files[i].mtime = now.Add(-time.Duration(i) * time.Hour)
+	}
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter}, tea.KeyMsg{Type: tea.KeyDown})
+
+	tests := []struct {
+		name     string
+		keys     []tea.Msg
+		group    int
+		expected []int
+	}{
+		{name: "All but current", keys: []tea.Msg{keyRune('a')}, group: 0, expected: []int{0, 2, 3}},
+		{name: "Invert", keys: []tea.Msg{keyRune('i')}, group: 0, expected: []int{1}},
+		{name: "Clear group", keys: []tea.Msg{keyRune('c')}, group: 0, expected: nil},
+		{name: "Keep oldest by default", keys: []tea.Msg{keyRune('m')}, group: 0, expected: []int{0, 1, 2}},
+		{name: "Keep rule on every group", keys: []tea.Msg{keyRune('M')}, group: 1, expected: []int{1, 2}},
+		{name: "Clear all", keys: []tea.Msg{keyRune('C')}, group: 1, expected: nil},
+	}
+	for _, tt := range tests {
+		m = update(m, tt.keys...)
+		if got := selectedIndices(m, tt.group); !equal(got, tt.expected) {
+			t.Errorf("%s: expected %v selected in group %d, got: %v", tt.name, tt.expected, tt.group, got)
+		}
+	}
+}
+
+func TestSelectInDir(t *testing.T) {
+	m := testModel(0, 0, 0)
+	m.groups[0].files = []dupFile{{path: "/srv/a"}, {path: "/backup/a"}, {path: "/backup/old/a"}}
+	m.groups[1].files = []dupFile{{path: "/backup/b"}, {path: "/backup/old/b"}}
+	m.groups[2].files = []dupFile{{path: "/srv/c"}, {path: "/home/c"}}
+	m.groups[0].size, m.groups[1].size = 1024, 2048
+
+	if n := m.selectInDir("/backup"); n != 3 {
+		t.Errorf("expected 3 files selected, got: %d", n)
+	}
+	if got := selectedIndices(m, 0); !equal(got, []int{1, 2}) {
+		t.Errorf("expected both backups selected in group 0, got: %v", got)
+	}
+	// Every file of group 1 is in /backup, the first one is kept.
+	if got := selectedIndices(m, 1); !equal(got, []int{1}) {
+		t.Errorf("expected a copy to be kept in group 1, got: %v", got)
+	}
+	if got := selectedIndices(m, 2); got != nil {
+		t.Errorf("expected nothing selected in group 2, got: %v", got)
+	}
+
+	totals := m.selectionTotals()
+	if !strings.Contains(totals, "3 files in 2 groups, 4.0 KiB in total") || strings.Contains(totals, "Every copy") {
+		t.Errorf("unexpected totals: %q", totals)
+	}
+	m.selected[1][0] = struct{}{}
+	if totals := m.selectionTotals(); !strings.Contains(totals, "Every copy is selected in 1 groups") {
+		t.Errorf("expected a warning about group 1, got: %q", totals)
+	}
+}
+
+func TestModelRefusesDeletingEveryCopy(t *testing.T) {
+	m := testModel(2, 2)
+	m = update(m, tea.KeyMsg{Type: tea.KeyEnter}, keyRune('i'), keyRune('d'))
+	if !m.showConfirm || !strings.Contains(m.View(), "Unselect a copy") {
+		t.Fatalf("expected the confirm dialog to ask to unselect a copy, got: %q", m.View())
+	}
+	m = update(m, keyRune('y'))
+	if !m.showConfirm || len(m.log) != 0 {
+		t.Error("expected y to be refused while every copy of a group is selected")
+	}
+	m = update(m, keyRune('n'))
+	if m.showConfirm {
+		t.Error("expected n to close the dialog")
+	}
+
+	// Trashed files can be restored, so trashing every copy is allowed.
+	m.actionOpts.trash = true
+	m = update(m, keyRune('d'))
+	if m.keepsNoCopy() || strings.Contains(m.View(), "Unselect a copy") {
+		t.Error("expected trashing every copy to be allowed")
+	}
+}
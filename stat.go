@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// sysInfo holds the platform specific parts of a file's metadata. Fields are
+// zero on platforms that do not expose them, except for uid and gid which are
+// -1.
+type sysInfo struct {
+	dev   uint64
+	ino   uint64
+	uid   int
+	gid   int
+	nlink uint64
+}
+
+// dupFile is a file that belongs to a duplicate group, along with the
+// metadata observed when its content was confirmed equal to the others.
+type dupFile struct {
+	path  string
+	mtime time.Time
+	mode  os.FileMode
+	sys   sysInfo
+}
+
+func statDupFile(path string) (dupFile, error) {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return dupFile{}, err
+	}
+	return dupFile{
+		path:  path,
+		mtime: fi.ModTime(),
+		mode:  fi.Mode(),
+		sys:   statSys(fi),
+	}, nil
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+func statSys(fi os.FileInfo) sysInfo {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return sysInfo{uid: -1, gid: -1}
+	}
+	return sysInfo{
+		dev:   uint64(st.Dev),
+		ino:   uint64(st.Ino),
+		uid:   int(st.Uid),
+		gid:   int(st.Gid),
+		nlink: uint64(st.Nlink),
+	}
+}
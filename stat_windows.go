@@ -0,0 +1,11 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// statSys returns an empty sysInfo on Windows, where os.FileInfo does not
+// expose device and inode numbers, owners or link counts.
+func statSys(fi os.FileInfo) sysInfo {
+	return sysInfo{uid: -1, gid: -1}
+}
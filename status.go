@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+var (
+	spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	statusStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+)
+
+// tickMsg advances the spinner and the elapsed time while the scan runs.
+type tickMsg time.Time
+
+func tick() tea.Cmd {
+	return tea.Tick(100*time.Millisecond, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+// statusView renders the status bar: whether the scan is still running, how
+// far it got, the groups found so far and the time it took.
+func (m model) statusView() string {
+	icon := "✔ Scan complete"
+	elapsed := m.finished.Sub(m.started)
+	if m.scanning {
+		icon = spinnerFrames[m.spinner%len(spinnerFrames)] + " Scanning"
+		elapsed = time.Since(m.started)
+	}
+
+	status := icon
+	if s := m.stats; s != nil {
+		switch {
+		case m.scanning && !s.walkDone.Load():
+			status += fmt.Sprintf(" • %d files found", s.walked.Load())
+		case m.scanning:
+			status += fmt.Sprintf(" • %d/%d files • %s/%s compared",
+				s.filesDone.Load(), s.files.Load(), formatBytes(s.bytesDone.Load()), formatBytes(s.bytes.Load()))
+		default:
+			status += fmt.Sprintf(" • %d files • %s compared", s.files.Load(), formatBytes(s.bytes.Load()))
+		}
+	}
+	status += fmt.Sprintf(" • %d groups • %s", len(m.groups), elapsed.Truncate(time.Second))
+	if s := m.stats; s != nil && s.errors.Load() > 0 {
+		status += fmt.Sprintf(" • %d errors", s.errors.Load())
+	}
+	if m.width > 0 {
+		status = truncateEnd(status, m.width)
+	}
+	return statusStyle.Render(status)
+}
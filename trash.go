@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// trashFile moves path to the trash following the freedesktop.org Trash
+// specification and returns where the file now lives. Files on the same
+// device as the home trash ($XDG_DATA_HOME/Trash) go there; files on other
+// devices go to the trash directory at the top of their own volume, so they
+// are never copied across devices unless that directory cannot be used. When
+// they are, warning says why.
+func trashFile(path string) (dst, warning string, err error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+	fi, err := os.Lstat(abs)
+	if err != nil {
+		return "", "", err
+	}
+
+	dir, err := homeTrashDir()
+	if err != nil {
+		return "", "", err
+	}
+	if dev := statSys(fi).dev; dev != deviceOf(dir) {
+		if volDir, err := volumeTrashDir(abs, dev); err == nil {
+			dir = volDir
+		} else {
+			warning = fmt.Sprintf("copied across devices to the home trash, the trash of its volume cannot be used: %v", err)
+		}
+	}
+
+	name, infoPath, err := createTrashInfo(dir, abs)
+	if err != nil {
+		return "", "", err
+	}
+	dst = filepath.Join(dir, "files", name)
+	if err := moveFile(abs, dst); err != nil {
+		os.Remove(infoPath)
+		return "", "", err
+	}
+	return dst, warning, nil
+}
+
+// homeTrashDir returns the home trash directory, creating it if needed.
+func homeTrashDir() (string, error) {
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	dir := filepath.Join(dataHome, "Trash")
+	return dir, makeTrashDir(dir)
+}
+
+// volumeTrashDir returns the trash directory of the volume holding path,
+// whose device is dev, creating it if needed. Per the specification an
+// administrator provided $topdir/.Trash/$uid is preferred, as long as .Trash
+// is a real directory with the sticky bit set, over $topdir/.Trash-$uid.
+func volumeTrashDir(path string, dev uint64) (string, error) {
+	topdir := filepath.Dir(path)
+	for {
+		parent := filepath.Dir(topdir)
+		if parent == topdir || deviceOf(parent) != dev {
+			break
+		}
+		topdir = parent
+	}
+
+	uid := strconv.Itoa(os.Getuid())
+	shared := filepath.Join(topdir, ".Trash")
+	if fi, err := os.Lstat(shared); err == nil && fi.IsDir() && fi.Mode()&os.ModeSticky != 0 {
+		dir := filepath.Join(shared, uid)
+		if err := makeTrashDir(dir); err == nil {
+			return dir, nil
+		}
+	}
+
+	dir := filepath.Join(topdir, ".Trash-"+uid)
+	return dir, makeTrashDir(dir)
+}
+
+func makeTrashDir(dir string) error {
+	for _, sub := range []string{"files", "info"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deviceOf returns the device number of path, or 0 if it cannot be read.
+func deviceOf(path string) uint64 {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return 0
+	}
+	return statSys(fi).dev
+}
+
+// createTrashInfo reserves a name in the trash directory dir for the file at
+// path by exclusively creating its .trashinfo file, and returns the name and
+// the path of the info file.
+func createTrashInfo(dir, path string) (string, string, error) {
+	content := "[Trash Info]\n" +
+		"Path=" + (&url.URL{Path: filepath.ToSlash(path)}).EscapedPath() + "\n" +
+		"DeletionDate=" + time.Now().Format("2006-01-02T15:04:05") + "\n"
+
+	base := filepath.Base(path)
+	for i := 1; i < 10000; i++ {
+		name := base
+		if i > 1 {
+			name = base + "." + strconv.Itoa(i)
+		}
+		if _, err := os.Lstat(filepath.Join(dir, "files", name)); err == nil {
+			continue
+		}
+
+		infoPath := filepath.Join(dir, "info", name+".trashinfo")
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := f.WriteString(content); err != nil {
+			f.Close()
+			os.Remove(infoPath)
+			return "", "", err
+		}
+		if err := f.Close(); err != nil {
+			os.Remove(infoPath)
+			return "", "", err
+		}
+		return name, infoPath, nil
+	}
+	return "", "", fmt.Errorf("cannot trash %s: no free name in %s", path, dir)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTrashFile(t *testing.T) {
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "my file.txt")
+
+	var trashed []string
+	for _, content := range []string{"first", "second"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		dst, warning, err := trashFile(path)
+		if err != nil {
+			t.Fatalf("trashFile failed: %v", err)
+		}
+		if warning != "" {
+			t.Errorf("expected no warning, got: %q", warning)
+		}
+		assertFileExists(t, path, false)
+		trashed = append(trashed, dst)
+	}
+
+	expected := []string{
+		filepath.Join(dataHome, "Trash", "files", "my file.txt"),
+		filepath.Join(dataHome, "Trash", "files", "my file.txt.2"),
+	}
+	for i, content := range []string{"first", "second"} {
+		if trashed[i] != expected[i] {
+			t.Errorf("expected file to be trashed to %s, got: %s", expected[i], trashed[i])
+		}
+		b, err := os.ReadFile(trashed[i])
+		if err != nil || string(b) != content {
+			t.Errorf("expected %s to contain %q, got: %q, %v", trashed[i], content, b, err)
+		}
+
+		info, err := os.ReadFile(filepath.Join(dataHome, "Trash", "info", filepath.Base(expected[i])+".trashinfo"))
+		if err != nil {
+			t.Fatalf("Failed to read trashinfo: %v", err)
+		}
+		lines := strings.Split(string(info), "\n")
+		if lines[0] != "[Trash Info]" {
+			t.Errorf("unexpected trashinfo header %q", lines[0])
+		}
+		if want := "Path=" + filepath.ToSlash(tempDir) + "/my%20file.txt"; lines[1] != want {
+			t.Errorf("expected %q, got %q", want, lines[1])
+		}
+		if !strings.HasPrefix(lines[2], "DeletionDate=") || len(lines[2]) != len("DeletionDate=2006-01-02T15:04:05") {
+			t.Errorf("unexpected deletion date line %q", lines[2])
+		}
+	}
+}
+
+func TestRemoveFileTrash(t *testing.T) {
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	path := filepath.Join(t.TempDir(), "file.txt")
+	if err := os.WriteFile(path, []byte("test"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	opts := actionOptions{trash: true}
+	if err := opts.removeFile(path); err != nil {
+		t.Fatalf("removeFile failed: %v", err)
+	}
+	assertFileExists(t, path, false)
+	assertFileExists(t, filepath.Join(dataHome, "Trash", "files", "file.txt"), true)
+	if verb := opts.removeVerb(); verb != "trashed" {
+		t.Errorf("expected verb %q, got: %q", "trashed", verb)
+	}
+}
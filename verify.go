@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// verifyTarget checks that the file of t, and the copy kept in its place,
+// are still the ones that were found to be duplicates: same size,
+// modification time and inode as when they were scanned. With content set,
+// their content is compared again too, or hashed when no copy is kept. Time
+// can pass between the scan and an action, and acting on a file edited in
+// the meantime would lose data.
+func verifyTarget(t actionTarget, content bool) error {
+	if err := verifyUnchanged(t.file, t.size); err != nil {
+		return err
+	}
+	if t.keep.path != "" {
+		if err := verifyUnchanged(t.keep, t.size); err != nil {
+			return err
+		}
+	}
+	if !content {
+		return nil
+	}
+
+	switch {
+	case t.keep.path != "":
+		equal, err := filesAreEqual(t.file.path, t.keep.path)
+		if err != nil {
+			return err
+		}
+		if !equal {
+			return fmt.Errorf("%s no longer has the same content as %s", t.file.path, t.keep.path)
+		}
+	case t.hash != "":
+		hash, err := createFileHash(t.file.path)
+		if err != nil {
+			return err
+		}
+		if hash != t.hash {
+			return fmt.Errorf("%s changed since it was scanned (content)", t.file.path)
+		}
+	}
+	return nil
+}
+
+// verifyUnchanged compares the file f describes with its metadata at scan
+// time, size being the size of its group.
+func verifyUnchanged(f dupFile, size int64) error {
+	fi, err := os.Lstat(f.path)
+	if err != nil {
+		return err
+	}
+
+	var changed []string
+	if fi.Size() != size {
+		changed = append(changed, "size")
+	}
+	if !fi.ModTime().Equal(f.mtime) {
+		changed = append(changed, "modification time")
+	}
+	if sys := statSys(fi); sys.dev != f.sys.dev || sys.ino != f.sys.ino {
+		changed = append(changed, "inode")
+	}
+	if len(changed) > 0 {
+		return fmt.Errorf("%s changed since it was scanned (%s)", f.path, strings.Join(changed, ", "))
+	}
+	return nil
+}
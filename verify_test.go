@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(t *testing.T, dup, keep string)
+		content bool
+		wantErr string
+	}{
+		{
+			name:   "Unchanged",
+			change: func(t *testing.T, dup, keep string) {},
+		},
+		{
+			name: "Duplicate grown",
+			change: func(t *testing.T, dup, keep string) {
+				writeTestFile(t, dup, "same content, edited")
+			},
+			wantErr: "size",
+		},
+		{
+			name: "Kept copy touched",
+			change: func(t *testing.T, dup, keep string) {
+				mtime := time.Now().Add(time.Hour)
+				if err := os.Chtimes(keep, mtime, mtime); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "modification time",
+		},
+		{
+			name: "Duplicate replaced",
+			change: func(t *testing.T, dup, keep string) {
+				fi, err := os.Stat(dup)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tmp := dup + ".tmp"
+				writeTestFile(t, tmp, "same content")
+				if err := os.Chtimes(tmp, fi.ModTime(), fi.ModTime()); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Rename(tmp, dup); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "inode",
+		},
+		{
+			name: "Kept copy removed",
+			change: func(t *testing.T, dup, keep string) {
+				if err := os.Remove(keep); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "no such file",
+		},
+		{
+			name:    "Content unchanged",
+			change:  func(t *testing.T, dup, keep string) {},
+			content: true,
+		},
+		{
+			name:    "Content edited in place",
+			change:  editInPlace,
+			content: true,
+			wantErr: "same content as",
+		},
+		{
+			name:   "Content edited in place without content check",
+			change: editInPlace,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			dup := filepath.Join(tempDir, "dup.txt")
+			keep := filepath.Join(tempDir, "keep.txt")
+			writeTestFile(t, dup, "same content")
+			writeTestFile(t, keep, "same content")
+
+			g, err := newDupGroup(int64(len("same content")), "", []string{keep, dup})
+			if err != nil {
+				t.Fatal(err)
+			}
+			target := groupTargets(g, 0)[0]
+
+			tt.change(t, dup, keep)
+
+			err = verifyTarget(target, tt.content)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPerformActionRefusesChangedFile(t *testing.T) {
+	tempDir := t.TempDir()
+	g := applyTestGroup(t, tempDir)
+	writeTestFile(t, g.files[1].path, "edited")
+
+	rec := performAction("delete", groupTargets(g, 0)[0], actionOptions{}, true)
+	if !strings.Contains(rec.Error, "changed since it was scanned") {
+		t.Errorf("expected the deletion to be refused, got: %q", rec.Error)
+	}
+	assertFileExists(t, g.files[1].path, true)
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+}
+
+// editInPlace overwrites dup with content of the same size and restores its
+// modification time, a change only a content comparison detects.
+func editInPlace(t *testing.T, dup, keep string) {
+	fi, err := os.Stat(dup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(dup, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("SAME"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dup, fi.ModTime(), fi.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+}